@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jw4/ignitia.go/pkg/collect"
+	"github.com/jw4/ignitia.go/pkg/collect/fake"
 	"github.com/jw4/ignitia.go/pkg/model"
+	"github.com/jw4/ignitia.go/pkg/query"
 	"github.com/jw4/ignitia.go/pkg/web"
 
 	_ "github.com/jw4/ignitia.go/pkg/model/persistence"
@@ -17,6 +29,22 @@ import (
 var version = "dev"
 
 func main() {
+	action := "help"
+	if len(os.Args) > 1 {
+		action = os.Args[1]
+	}
+
+	// The fake portal needs no model.
+	if action == "fake-portal" {
+		doFakePortal(os.Args[2:])
+		return
+	}
+
+	if err := loadPolicy(); err != nil {
+		fmt.Fprintf(os.Stderr, "error loading policy: %v\n", err)
+		os.Exit(1)
+	}
+
 	mod := model.New(os.Getenv("IGNITIA_DB"))
 	if mod == nil {
 		fmt.Fprintf(os.Stderr, "unable to open model for %q\n", os.Getenv("IGNITIA_DB"))
@@ -28,33 +56,205 @@ func main() {
 		web.Assets(os.Getenv("PUBLIC_ASSETS")),
 		web.Templates(os.Getenv("TEMPLATES")),
 	}
-	webSession := web.NewSession(mod, opts...)
 
-	action := "help"
-	if len(os.Args) > 1 {
-		action = os.Args[1]
+	if interval, ok := envDuration("IGNITIA_POLL_INTERVAL"); ok {
+		opts = append(opts, web.PollInterval(interval))
+	}
+
+	if timeout, ok := envDuration("IGNITIA_SERVE_TIMEOUT"); ok {
+		opts = append(opts, web.RequestTimeout(timeout))
 	}
+	webSession := web.NewSession(mod, opts...)
 
 	switch action {
 	case "serve":
 		doServe(webSession)
 	case "html":
 		doHTML(webSession)
+	case "list":
+		doList(mod, os.Args[2:])
 	case "due":
 		doPrint(mod, isDue)
 	case "overdue":
 		doPrint(mod, isOverdue)
+	case "snapshots":
+		doSnapshots(mod)
+	case "diff":
+		doDiff(mod, os.Args[2:])
 	case "snapshot":
 		doSnapshot(mod,
 			collect.NewSession(
 				os.Getenv("IGNITIA_BASE_URL"),
 				os.Getenv("IGNITIA_USERNAME"),
-				os.Getenv("IGNITIA_PASSWORD")))
+				os.Getenv("IGNITIA_PASSWORD"),
+				collectOptions(mod)...))
+	case "reprocess":
+		doReprocess(mod,
+			collect.NewSession(
+				os.Getenv("IGNITIA_BASE_URL"),
+				os.Getenv("IGNITIA_USERNAME"),
+				os.Getenv("IGNITIA_PASSWORD"),
+				collectOptions(mod)...),
+			os.Args[2:])
 	default:
 		doHelp()
 	}
 }
 
+// loadPolicy sets the due and overdue policy from IGNITIA_POLICY, and the
+// school calendar from IGNITIA_CALENDAR and IGNITIA_CALENDAR_OFF.
+func loadPolicy() error {
+	policy := model.DefaultPolicy()
+
+	if path := os.Getenv("IGNITIA_POLICY"); path != "" {
+		var err error
+		if policy, err = model.LoadPolicy(path); err != nil {
+			return err
+		}
+	}
+
+	if path := os.Getenv("IGNITIA_CALENDAR"); path != "" {
+		var daysOff *regexp.Regexp
+		if pattern := os.Getenv("IGNITIA_CALENDAR_OFF"); pattern != "" {
+			var err error
+			if daysOff, err = regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("IGNITIA_CALENDAR_OFF: %w", err)
+			}
+		}
+
+		calendar, err := model.LoadCalendar(path, daysOff)
+		if err != nil {
+			return err
+		}
+
+		policy.Calendar = calendar
+	}
+
+	model.SetPolicy(policy)
+
+	return nil
+}
+
+// envInt returns the integer in the environment variable name, and whether it
+// is set. A value that does not parse ends the program.
+func envInt(name string) (int, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing %s: %v\n", name, err)
+		os.Exit(1)
+	}
+
+	return n, true
+}
+
+// envFloat returns the number in the environment variable name, and whether
+// it is set. A value that does not parse ends the program.
+func envFloat(name string) (float64, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing %s: %v\n", name, err)
+		os.Exit(1)
+	}
+
+	return f, true
+}
+
+// envDuration returns the duration in the environment variable name, and
+// whether it is set. A value that does not parse ends the program.
+func envDuration(name string) (time.Duration, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing %s: %v\n", name, err)
+		os.Exit(1)
+	}
+
+	return d, true
+}
+
+func collectOptions(mod model.Full) []collect.Option {
+	var opts []collect.Option
+
+	if n, ok := envInt("IGNITIA_CONCURRENCY"); ok {
+		opts = append(opts, collect.Concurrency(n))
+	}
+
+	if rps, ok := envFloat("IGNITIA_RATE_LIMIT"); ok {
+		opts = append(opts, collect.RateLimit(rps))
+	}
+
+	if n, ok := envInt("IGNITIA_RETRIES"); ok {
+		opts = append(opts, collect.Retries(n))
+	}
+
+	if d, ok := envDuration("IGNITIA_BACKOFF"); ok {
+		opts = append(opts, collect.Backoff(d))
+	}
+
+	if n, ok := envInt("IGNITIA_PAGE_SIZE"); ok {
+		opts = append(opts, collect.PageSize(n))
+	}
+
+	if columns := os.Getenv("IGNITIA_COLUMNS"); columns != "" {
+		opts = append(opts, collect.Columns(collect.ParseColumns(columns)...))
+	}
+
+	if secret := os.Getenv("IGNITIA_COOKIE_KEY"); secret != "" {
+		key, err := collect.ParseCookieKey(secret)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: not saving cookies: IGNITIA_COOKIE_KEY: %v\n", err)
+		} else if path := os.Getenv("IGNITIA_COOKIE_JAR"); path != "" {
+			opts = append(opts, collect.Cookies(collect.FileCookies(path), key))
+		} else if store, ok := mod.(collect.CookieStore); ok {
+			opts = append(opts, collect.Cookies(store, key))
+		}
+	}
+
+	if archive := openArchive(); archive != nil {
+		opts = append(opts, collect.ArchiveTo(archive))
+	}
+
+	if dir := os.Getenv("IGNITIA_RECORD"); dir != "" {
+		opts = append(opts, collect.Record(dir))
+	}
+
+	if dir := os.Getenv("IGNITIA_REPLAY"); dir != "" {
+		opts = append(opts, collect.Replay(dir))
+	}
+
+	return opts
+}
+
+// openArchive opens the raw response archive named by IGNITIA_ARCHIVE: a NATS
+// URL, or otherwise a directory.
+func openArchive() collect.Archive {
+	conn := os.Getenv("IGNITIA_ARCHIVE")
+
+	switch {
+	case conn == "":
+		return nil
+	case strings.HasPrefix(conn, "nats://"):
+		archive, _ := model.New(conn).(collect.Archive)
+		return archive
+	default:
+		return collect.DirArchive(conn)
+	}
+}
+
 func doHelp() { fmt.Fprint(os.Stderr, helpText) }
 
 func doServe(session *web.Session) {
@@ -62,12 +262,63 @@ func doServe(session *web.Session) {
 	fmt.Fprintf(os.Stderr, "Version: %s\n", version)
 	fmt.Fprintf(os.Stderr, "Serving on %s\n", bind)
 
+	stop, err := session.Watch()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error watching for updates: %v\n", err)
+		os.Exit(-1)
+	}
+	defer stop()
+
 	if err := http.ListenAndServe(bind, session); err != nil {
 		fmt.Fprintf(os.Stderr, "error serving: %v\n", err)
 		os.Exit(-1)
 	}
 }
 
+func doFakePortal(args []string) {
+	flags := flag.NewFlagSet("fake-portal", flag.ExitOnError)
+	fixture := flags.String("fixture", "", "JSON snapshot to serve instead of the demo data")
+	username := flags.String("username", "", "only accept this username")
+	password := flags.String("password", "", "only accept this password")
+	loginError := flags.Bool("login-error", false, "reject every login")
+	expireAfter := flags.Int("expire-after", 0, "expire sessions after this many data requests")
+	emptyRows := flags.Bool("empty-rows", false, "answer every assignment listing with empty rows")
+	latency := flags.Duration("latency", 0, "delay every data request")
+	_ = flags.Parse(args)
+
+	data := fake.Demo()
+	if *fixture != "" {
+		var err error
+		if data, err = fake.Load(*fixture); err != nil {
+			fmt.Fprintf(os.Stderr, "error loading fixture: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opts := []fake.Option{fake.ExpireAfter(*expireAfter), fake.Latency(*latency)}
+
+	if *username != "" {
+		opts = append(opts, fake.Credentials(*username, *password))
+	}
+
+	if *loginError {
+		opts = append(opts, fake.LoginError())
+	}
+
+	if *emptyRows {
+		opts = append(opts, fake.EmptyRows())
+	}
+
+	bind := os.Getenv("BIND")
+	fmt.Fprintf(os.Stderr, "Version: %s\n", version)
+	fmt.Fprintf(os.Stderr, "Fake portal on %s\n", bind)
+
+	if err := http.ListenAndServe(bind, fake.New(data, opts...)); err != nil {
+		fmt.Fprintf(os.Stderr, "error serving: %v\n", err)
+		os.Exit(-1)
+	}
+}
+
 func doHTML(session *web.Session) {
 	session.DebugWriter = io.Discard
 
@@ -82,92 +333,403 @@ func doHTML(session *web.Session) {
 	}
 }
 
-func doPrint(mod model.Read, with func(*model.Assignment) bool) {
+func doList(mod model.Read, args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	where := flags.String("where", "", "only list assignments matching the query")
+	_ = flags.Parse(args)
+
+	q, err := query.Parse(*where)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing query: %v\n", err)
+		os.Exit(1)
+	}
+
+	doPrint(mod, q.Match)
+}
+
+func doPrint(mod model.Read, with query.Predicate) {
 	data, err := mod.Data()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error printing: %v\n", err)
 	}
 
-	print(filter(data, with), os.Stdout)
+	print(data.Filter(with), os.Stdout)
 }
 
-func doSnapshot(writer model.Write, reader model.Read) {
+func doSnapshot(mod model.Full, session *collect.Session) {
 	fmt.Fprintf(os.Stderr, "Version: %s\n", version)
 
-	if err := writer.Save(reader); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if timeout, ok := envDuration("IGNITIA_SNAPSHOT_TIMEOUT"); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := model.SaveContext(ctx, mod, model.Fallback(warnFailures{session}, mod))
+
+	// An unchanged snapshot is not stored, so neither are its responses.
+	if history, ok := mod.(model.History); ok && err == nil {
+		if err := session.PruneArchive(history); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: pruning archive: %v\n", err)
+		}
+	}
+
+	if path := os.Getenv("IGNITIA_METRICS_FILE"); path != "" {
+		if err := collect.WriteMetrics(path); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: writing metrics: %v\n", err)
+		}
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error snapshotting: %v\n", err)
 		os.Exit(-1)
 	}
 }
 
-func print(students []model.Student, out io.Writer) {
-	for _, student := range students {
-		if len(student.Courses) == 0 {
+// warnFailures reports the parts of a snapshot that could not be collected.
+type warnFailures struct{ model.Read }
+
+func (w warnFailures) Data() (model.Data, error) { return w.DataContext(context.Background()) }
+
+func (w warnFailures) DataContext(ctx context.Context) (model.Data, error) {
+	data, err := model.DataContext(ctx, w.Read)
+	if err != nil {
+		return data, err
+	}
+
+	for _, failure := range data.Failures {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", failure)
+	}
+
+	return data, nil
+}
+
+// doReprocess rebuilds the archived snapshots in the given range with the
+// current parsing code, rewriting those whose content has changed.
+func doReprocess(mod model.Full, session *collect.Session, args []string) {
+	history, ok := mod.(model.History)
+	rewriter, canRewrite := mod.(model.Rewriter)
+	if !ok || !canRewrite {
+		fmt.Fprintf(os.Stderr, "snapshot history not supported for %q\n", os.Getenv("IGNITIA_DB"))
+		os.Exit(1)
+	}
+
+	archive := openArchive()
+	if archive == nil {
+		fmt.Fprintln(os.Stderr, "no archive: set IGNITIA_ARCHIVE")
+		os.Exit(1)
+	}
+
+	from, to, err := reprocessRange(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error choosing snapshots: %v\n", err)
+		os.Exit(1)
+	}
+
+	snapshots, err := archive.RawSnapshots()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error listing archived snapshots: %v\n", err)
+		os.Exit(-1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := false
+	for _, asOf := range snapshots {
+		if asOf.Before(from) || asOf.After(to) {
 			continue
 		}
 
-		fmt.Fprintf(out, "\nStudent: %s\n", html.UnescapeString(student.DisplayName))
-
-		for _, course := range student.SortedCourses() {
-			if len(course.Assignments) == 0 {
-				continue
+		status, err := reprocess(ctx, history, rewriter, session, asOf)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintf(os.Stderr, "error reprocessing: %v\n", ctx.Err())
+				os.Exit(-1)
 			}
 
-			fmt.Fprintf(out, "\n  Course: %s; %d assignments\n", course.Title, len(course.Assignments))
+			status, failed = fmt.Sprintf("error: %v", err), true
+		}
 
-			for _, assignment := range course.SortedAssignments() {
-				fmt.Fprintf(out, "    Assignment: %s\n", assignment.String())
-			}
+		fmt.Fprintf(os.Stdout, "%s  %s\n", asOf.Format(time.RFC3339Nano), status)
+	}
+
+	if failed {
+		os.Exit(-1)
+	}
+}
+
+// reprocess rebuilds the snapshot taken at asOf, carrying over what failed to
+// collect from the snapshot before it as the original snapshot did, and
+// stores it unless the snapshot in effect at asOf already has that content.
+func reprocess(
+	ctx context.Context, history model.History, rewriter model.Rewriter, session *collect.Session, asOf time.Time,
+) (string, error) {
+	data, err := session.Reprocess(ctx, asOf)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data.Failures) > 0 {
+		if prev, err := model.DataAsOfContext(ctx, history, asOf.Add(-time.Nanosecond)); err == nil {
+			data.Carry(prev)
 		}
 	}
+
+	status := "added"
+
+	stored, err := model.DataAsOfContext(ctx, history, asOf)
+	switch {
+	case err == nil:
+		digest, err := model.Digest(data)
+		if err != nil {
+			return "", err
+		}
+
+		if previous, err := model.Digest(stored); err == nil && previous == digest {
+			return "unchanged", nil
+		}
+
+		if stored.AsOf.Equal(asOf) {
+			status = "rewritten"
+		}
+	case !errors.Is(err, model.ErrNoSnapshot):
+		return "", err
+	}
+
+	return status, rewriter.Replace(data)
 }
 
-func isDue(a *model.Assignment) bool     { return a.IsDue() }
-func isOverdue(a *model.Assignment) bool { return a.IsOverdue() }
+// reprocessRange picks the archived snapshots to rebuild: those between the
+// two times given on the command line, from the one given onwards, or by
+// default all of them.
+func reprocessRange(args []string) (time.Time, time.Time, error) {
+	from, to := time.Time{}, time.Now()
+
+	if len(args) > 2 {
+		return from, to, fmt.Errorf("expected at most two times, got %d", len(args))
+	}
 
-func filter(data model.Data, with func(*model.Assignment) bool) []model.Student {
-	var filtered []model.Student
+	for i, arg := range args {
+		t, err := model.ParseAsOf(arg)
+		if err != nil {
+			return from, to, err
+		}
+
+		if i == 0 {
+			from = t
+		} else {
+			to = t
+		}
+	}
+
+	return from, to, nil
+}
+
+func doSnapshots(mod model.Full) {
+	history, ok := mod.(model.History)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "snapshot history not supported for %q\n", os.Getenv("IGNITIA_DB"))
+		os.Exit(1)
+	}
+
+	snapshots, err := history.Snapshots()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error listing snapshots: %v\n", err)
+		os.Exit(-1)
+	}
+
+	for _, snapshot := range snapshots {
+		fmt.Fprintln(os.Stdout, snapshot.Format(time.RFC3339Nano))
+	}
+}
+
+func doDiff(mod model.Full, args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "write changes as JSON")
+	_ = flags.Parse(args)
+
+	history, ok := mod.(model.History)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "snapshot history not supported for %q\n", os.Getenv("IGNITIA_DB"))
+		os.Exit(1)
+	}
+
+	from, to, err := diffRange(history, flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error choosing snapshots: %v\n", err)
+		os.Exit(1)
+	}
+
+	old, err := history.DataAsOf(from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading snapshot as of %s: %v\n", from.Format(time.RFC3339), err)
+		os.Exit(-1)
+	}
+
+	cur, err := history.DataAsOf(to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading snapshot as of %s: %v\n", to.Format(time.RFC3339), err)
+		os.Exit(-1)
+	}
+
+	changes := model.Diff(old, cur)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(changes)
+
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "Changes from %s to %s\n\n", changes.From.Format(time.RFC1123), changes.To.Format(time.RFC1123))
+
+	for _, change := range changes.Changes {
+		fmt.Fprintf(os.Stdout, "  %s\n", change)
+	}
+}
+
+// diffRange picks the snapshots to compare: the two given on the command line,
+// the one given and the latest, or by default the two most recent.
+func diffRange(history model.History, args []string) (time.Time, time.Time, error) {
+	var when []time.Time
+
+	for _, arg := range args {
+		t, err := model.ParseAsOf(arg)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		when = append(when, t)
+	}
+
+	switch len(when) {
+	case 2:
+		return when[0], when[1], nil
+	case 1:
+		return when[0], time.Now(), nil
+	case 0:
+		snapshots, err := history.Snapshots()
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		if len(snapshots) < 2 {
+			return time.Time{}, time.Time{}, fmt.Errorf("need two snapshots to compare, have %d", len(snapshots))
+		}
+
+		return snapshots[len(snapshots)-2], snapshots[len(snapshots)-1], nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("expected at most two times, got %d", len(when))
+	}
+}
+
+func print(data model.Data, out io.Writer) {
 	for _, student := range data.SortedStudents() {
-		filteredCourses := map[int]*model.Course{}
+		if len(student.Courses) == 0 {
+			continue
+		}
 
-		for _, course := range student.Courses {
-			filteredAssignments := map[int]*model.Assignment{}
+		fmt.Fprintf(out, "\nStudent: %s\n", html.UnescapeString(student.DisplayName))
 
-			for _, assignment := range course.Assignments {
-				if with(assignment) {
-					filteredAssignments[assignment.ID] = assignment
-				}
+		for _, course := range student.SortedCourses() {
+			if len(course.Assignments) == 0 {
+				continue
 			}
 
-			if len(filteredAssignments) > 0 {
-				filteredCourses[course.ID] = &model.Course{
-					ID:          course.ID,
-					Title:       course.Title,
-					Assignments: filteredAssignments,
-				}
+			fmt.Fprintf(out, "\n  Course: %s; %d assignments", course.Title, len(course.Assignments))
+
+			if pace := course.Pace(); pace != nil {
+				fmt.Fprintf(out, "; %s", pace)
 			}
-		}
 
-		if len(filteredCourses) > 0 {
-			filtered = append(filtered, model.Student{
-				ID:          student.ID,
-				DisplayName: student.DisplayName,
-				Courses:     filteredCourses,
-			})
+			fmt.Fprintln(out)
+
+			for _, assignment := range course.SortedAssignments() {
+				fmt.Fprintf(out, "    Assignment: %s\n", assignment.String())
+			}
 		}
 	}
-
-	return filtered
 }
 
+func isDue(_ *model.Student, _ *model.Course, a *model.Assignment) bool     { return a.IsDue() }
+func isOverdue(_ *model.Student, _ *model.Course, a *model.Assignment) bool { return a.IsOverdue() }
+
 const helpText = `
 options:
 
   serve      serve web page
   html       render report in HTML
-  due        print due assignments
-  overdue    print overdue assignments
-  snapshot   update sqlite db
+  list       print assignments
+             [-where query]; such as
+             -where 'student:"Anna" course~math due<=+3d score<70'
+  due        print due assignments and the pace of their courses
+  overdue    print overdue assignments and the pace of their courses
+  snapshot   collect from the portal and save to IGNITIA_DB
+  snapshots  list stored snapshots
+  diff       print changes between snapshots
+             [-json] [from [to]]; defaults to the two latest
+  reprocess  rebuild snapshots from the responses archived in
+             IGNITIA_ARCHIVE with the current parsing code
+             [from [to]]; defaults to every archived snapshot
+  fake-portal
+             serve a fake Ignitia portal on BIND for offline use
+             [-fixture file] [-username u -password p] [-login-error]
+             [-expire-after n] [-empty-rows] [-latency d]
   help       display this help
 
+environment:
+
+  IGNITIA_DB         where snapshots are kept: a sqlite file (sqlite://path,
+                     *.db, *.sqlite, *.sqlite3) or a nats:// URL
+  IGNITIA_BASE_URL   the portal to collect from
+  IGNITIA_USERNAME   portal username
+  IGNITIA_PASSWORD   portal password
+  IGNITIA_SNAPSHOT_TIMEOUT
+                     give up on a snapshot after this long, such as 10m
+  IGNITIA_CONCURRENCY
+                     course requests to the portal at a time; default 4
+  IGNITIA_RATE_LIMIT requests per second to the portal, 0 for no limit;
+                     default 4
+  IGNITIA_RETRIES    times a failed request is retried; default 3
+  IGNITIA_BACKOFF    delay before the first retry, doubling for each one
+                     after; default 1s
+  IGNITIA_PAGE_SIZE  assignments requested per page; default 1000
+  IGNITIA_COLUMNS    assignment grid columns, in order, for responses that
+                     do not describe them: name[=field],...; default
+                     id,unit,title,type,progress,due,completed,score,status
+  IGNITIA_COOKIE_KEY base64 encoded 32 byte key; when set, the portal
+                     cookies are encrypted and kept between runs
+  IGNITIA_COOKIE_JAR file to keep the cookies in instead of IGNITIA_DB
+  IGNITIA_ARCHIVE    directory or nats:// URL keeping the raw portal
+                     responses of every snapshot, for reprocess
+  IGNITIA_RECORD     directory to record every exchange with the portal in
+  IGNITIA_REPLAY     directory of recorded exchanges to answer from instead
+                     of the portal
+  IGNITIA_METRICS_FILE
+                     file to write collection metrics to after a snapshot,
+                     for the node exporter's textfile collector
+  IGNITIA_POLICY     JSON file with the due, overdue and grading policy
+  IGNITIA_CALENDAR   days without school: an iCalendar file, or a file of
+                     dates and date ranges, one per line
+  IGNITIA_CALENDAR_OFF
+                     pattern matching the summary or categories of the
+                     iCalendar events without school; default
+                     holiday, break, vacation, recess, no school, day off
+                     or closed
+  BIND               address for serve and fake-portal, such as :8080
+  PUBLIC_ASSETS      directory of static files for serve
+  TEMPLATES          directory of HTML templates
+  IGNITIA_POLL_INTERVAL
+                     how often serve checks a sqlite store for new
+                     snapshots; default 1m, also used for values of zero
+                     or less
+  IGNITIA_SERVE_TIMEOUT
+                     how long a request to serve may wait on the store
+
 `
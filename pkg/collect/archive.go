@@ -0,0 +1,200 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// Archive keeps the raw responses from the portal, grouped by the snapshot
+// they were collected for, so that snapshots can be rebuilt from them.
+type Archive interface {
+	PutRaw(asOf time.Time, name string, body []byte) error
+	GetRaw(asOf time.Time, name string) ([]byte, error)
+	RawSnapshots() ([]time.Time, error)
+	DeleteRaw(asOf time.Time) error
+}
+
+// ErrNoArchive is returned when reprocessing without an archive, or when the
+// archive holds nothing for the snapshot.
+var ErrNoArchive = errors.New("no archived responses")
+
+// ArchiveTo keeps the raw responses of every snapshot in archive.
+func ArchiveTo(archive Archive) Option {
+	return func(s *Session) { s.archive = archive }
+}
+
+// DirArchive keeps the raw responses in a directory tree under path, with a
+// directory for each snapshot named by its time in Unix nanoseconds.
+func DirArchive(path string) Archive { return dirArchive(path) }
+
+type dirArchive string
+
+func (d dirArchive) dir(asOf time.Time) string {
+	return filepath.Join(string(d), strconv.FormatInt(asOf.UnixNano(), 10))
+}
+
+func (d dirArchive) PutRaw(asOf time.Time, name string, body []byte) error {
+	dir := d.dir(asOf)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, name), body, 0o600)
+}
+
+func (d dirArchive) GetRaw(asOf time.Time, name string) ([]byte, error) {
+	body, err := os.ReadFile(filepath.Join(d.dir(asOf), name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s for %s: %w", name, asOf.Format(time.RFC3339Nano), ErrNoArchive)
+	}
+
+	return body, err
+}
+
+func (d dirArchive) RawSnapshots() ([]time.Time, error) {
+	entries, err := os.ReadDir(string(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []time.Time
+	for _, entry := range entries {
+		nanos, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		snapshots = append(snapshots, time.Unix(0, nanos))
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Before(snapshots[j]) })
+
+	return snapshots, nil
+}
+
+func (d dirArchive) DeleteRaw(asOf time.Time) error { return os.RemoveAll(d.dir(asOf)) }
+
+// Names of the archived responses within a snapshot.
+const studentsName = "students.json"
+
+func coursesName(student *model.Student) string { return fmt.Sprintf("courses-%d.json", student.ID) }
+
+func detailsName(course *model.Course) string { return fmt.Sprintf("details-%d.json", course.ID) }
+
+func assignmentsName(course *model.Course, page int) string {
+	return fmt.Sprintf("assignments-%d-%d.json", course.ID, page)
+}
+
+type snapshotKey struct{}
+
+// withSnapshot marks the requests made with ctx as belonging to the snapshot
+// taken at asOf.
+func withSnapshot(ctx context.Context, asOf time.Time) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, asOf)
+}
+
+// keep archives a raw response for the snapshot ctx belongs to, if any.
+// Failing to archive is only warned about, since the snapshot itself is fine.
+func (s *Session) keep(ctx context.Context, name string, body []byte) {
+	asOf, ok := ctx.Value(snapshotKey{}).(time.Time)
+	if s.archive == nil || !ok {
+		return
+	}
+
+	if err := s.archive.PutRaw(asOf, name, body); err != nil {
+		s.logger.Printf("warning: archiving %s: %v\n", name, err)
+	}
+}
+
+// PruneArchive drops the responses archived for the last snapshot collected
+// unless history stored it, which it does not when nothing has changed.
+func (s *Session) PruneArchive(history model.History) error {
+	s.mu.Lock()
+	asOf := s.collected
+	s.mu.Unlock()
+
+	if s.archive == nil || asOf.IsZero() {
+		return nil
+	}
+
+	snapshots, err := history.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Equal(asOf) {
+			return nil
+		}
+	}
+
+	return s.archive.DeleteRaw(asOf)
+}
+
+// Reprocess rebuilds the snapshot taken at asOf from its archived responses,
+// using the current parsing code instead of the code that collected it.
+func (s *Session) Reprocess(ctx context.Context, asOf time.Time) (model.Data, error) {
+	if s.archive == nil {
+		return model.Data{AsOf: asOf}, ErrNoArchive
+	}
+
+	return gather(ctx, archived{session: s, asOf: asOf}, asOf, s.concurrency)
+}
+
+// archived is a source answering from the responses archived for a
+// snapshot.
+type archived struct {
+	session *Session
+	asOf    time.Time
+}
+
+func (a archived) students(ctx context.Context) ([]*model.Student, error) {
+	body, err := a.session.archive.GetRaw(a.asOf, studentsName)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStudents(body)
+}
+
+func (a archived) courses(ctx context.Context, student *model.Student) ([]*model.Course, error) {
+	body, err := a.session.archive.GetRaw(a.asOf, coursesName(student))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCourses(body, student)
+}
+
+func (a archived) courseDetails(ctx context.Context, course *model.Course) (*details, error) {
+	body, err := a.session.archive.GetRaw(a.asOf, detailsName(course))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCourseDetails(body)
+}
+
+func (a archived) assignmentPage(
+	ctx context.Context, student *model.Student, course *model.Course, page int,
+) (*assignmentResponseHelper, error) {
+	body, err := a.session.archive.GetRaw(a.asOf, assignmentsName(course, page))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.session.parseAssignmentPage(body, student, course)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/jw4/ignitia.go/pkg/model"
 )
@@ -16,127 +17,235 @@ var (
 	ErrMarshal    = errors.New("json marshaling error")
 )
 
-func ToAssignment(raw map[string]interface{}) (*model.Assignment, error) { // nolint: funlen,cyclop
-	id, ok := raw["id"].(float64) // nolint: varnamelen
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for cell id: got %T (%v), expected number [%w]",
-			raw["id"], raw["id"], ErrValidation)
-	}
+// Column maps a column of the assignment grid to an assignment field. Name is
+// the grid's name for the column and Field one of the fields named in
+// DefaultColumns; values in any other column are kept in Assignment.Extra.
+type Column struct {
+	Name  string
+	Field string
+}
 
-	assignment := &model.Assignment{ID: int(id)} // nolint: exhaustivestruct
+// DefaultColumns are the assignment grid's columns, in order, for responses
+// that do not describe them.
+var DefaultColumns = ParseColumns("id,unit,title,type,progress,due,completed,score,status")
 
-	items, ok := raw["cell"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for cell values: got %T (%v), expected []interface{} [%w]",
-			raw["cell"], raw["cell"], ErrValidation)
-	}
+// ParseColumns parses a comma separated list of grid column names, in order,
+// each optionally mapped to an assignment field as name=field.
+func ParseColumns(s string) []Column {
+	var columns []Column
 
-	const columns = 9
-	if len(items) != columns {
-		return nil, fmt.Errorf(
-			"unexpected length of items, got %d (%v), expected %d [%w]",
-			len(items), items, columns, ErrValidation)
-	}
+	for _, part := range strings.Split(s, ",") {
+		name, field, mapped := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
 
-	_, ok = items[0].(float64)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for ID (items[0]): got %T (%v), expected number [%w]",
-			items[0], items[0], ErrValidation)
-	}
+		if !mapped {
+			field = name
+		}
 
-	unit, ok := items[1].(float64)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for Unit (items[1]): got %T (%v), expected number [%w]",
-			items[1], items[1], ErrValidation)
+		columns = append(columns, Column{Name: name, Field: strings.ToLower(strings.TrimSpace(field))})
 	}
 
-	assignment.Unit = int(unit)
+	return columns
+}
 
-	title, ok := items[2].(string)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for Title (items[2]): got %T (%v), expected string [%w]",
-			items[2], items[2], ErrValidation)
-	}
+// ToAssignment converts a row of the assignment grid laid out as
+// DefaultColumns.
+func ToAssignment(raw map[string]interface{}) (*model.Assignment, error) {
+	return toAssignment(raw, DefaultColumns, drifts{})
+}
 
-	assignment.Title = title
+// drift is a difference between an assignment row and the expected columns.
+type drift struct {
+	kind   string
+	column string
+}
 
-	typ, ok := items[3].(string)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for Type (items[3]): got %T (%v), expected string [%w]",
-			items[3], items[3], ErrValidation)
+const (
+	driftExtra   = "extra"
+	driftMissing = "missing"
+	driftType    = "type"
+	driftID      = "id"
+)
+
+func (d drift) String() string {
+	switch d.kind {
+	case driftExtra:
+		return fmt.Sprintf("unexpected assignment column %q kept as extra", d.column)
+	case driftMissing:
+		return fmt.Sprintf("assignment column %q is missing", d.column)
+	case driftID:
+		return fmt.Sprintf("assignment column %q differs from the row id, kept as extra", d.column)
+	default:
+		return fmt.Sprintf("assignment column %q has an unexpected type, kept as extra", d.column)
 	}
+}
 
-	assignment.Type = typ
+// drifts counts the rows affected by each drift.
+type drifts map[drift]int
 
-	progress, ok := items[4].(float64)
+// toAssignment converts a row of the assignment grid. Cells are given either
+// in the order of columns or, when the grid does not repeat items, by name.
+// Only a row without an id fails, and the row id is kept over any id cell;
+// anything else that does not fit is recorded in found and kept in Extra.
+func toAssignment(raw map[string]interface{}, columns []Column, found drifts) (*model.Assignment, error) {
+	id, ok := number(raw["id"])
 	if !ok {
 		return nil, fmt.Errorf(
-			"unexpected type for Progress (items[4]): got %T (%v), expected number [%w]",
-			items[4], items[4], ErrValidation)
+			"unexpected type for cell id: got %T (%v), expected number [%w]",
+			raw["id"], raw["id"], ErrValidation)
 	}
 
-	assignment.Progress = int(progress)
+	values := map[string]interface{}{}
 
-	switch due := items[5].(type) {
-	case string:
-		assignment.Due = due
+	switch cell := raw["cell"].(type) {
+	case []interface{}:
+		for i, v := range cell {
+			if i < len(columns) {
+				values[columns[i].Name] = v
+			} else {
+				values[fmt.Sprintf("column%d", i+1)] = v
+			}
+		}
+	case map[string]interface{}:
+		values = cell
 	case nil:
+		for k, v := range raw {
+			values[k] = v
+		}
 	default:
 		return nil, fmt.Errorf(
-			"unexpected type for Due (items[5]): got %T (%v), expected string [%w]",
-			items[5], items[5], ErrValidation)
+			"unexpected type for cell values: got %T (%v), expected []interface{} [%w]",
+			raw["cell"], raw["cell"], ErrValidation)
 	}
 
-	switch completed := items[6].(type) {
-	case string:
-		assignment.Completed = completed
-	case nil:
-	default:
-		return nil, fmt.Errorf(
-			"unexpected type for Completed (items[6]): got %T (%v), expected string [%w]",
-			items[6], items[6], ErrValidation)
+	fields := map[string]string{}
+	for _, c := range columns {
+		fields[c.Name] = c.Field
 	}
 
-	score, ok := items[7].(float64)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for Score (items[7]): got %T (%v), expected number [%w]",
-			items[7], items[7], ErrValidation)
-	}
+	assignment := &model.Assignment{ID: int(id)} // nolint: exhaustivestruct
+	seen := map[string]bool{"id": true}
 
-	assignment.Score = int(score)
+	for name, v := range values {
+		field, ok := fields[name]
+		if !ok {
+			field = strings.ToLower(name)
+		}
 
-	status, ok := items[8].(string)
-	if !ok {
-		return nil, fmt.Errorf(
-			"unexpected type for Status (items[8]): got %T (%v), expected string [%w]",
-			items[8], items[8], ErrValidation)
+		set, known := assignmentFields[field]
+		switch {
+		case field == "id":
+			if n, ok := number(v); ok && int(n) == assignment.ID {
+				continue
+			}
+
+			found[drift{kind: driftID, column: name}]++
+		case !known:
+			found[drift{kind: driftExtra, column: name}]++
+		case !set(assignment, v):
+			found[drift{kind: driftType, column: name}]++
+		default:
+			seen[field] = true
+			continue
+		}
+
+		if assignment.Extra == nil {
+			assignment.Extra = map[string]interface{}{}
+		}
+
+		assignment.Extra[name] = v
 	}
 
-	assignment.Status = status
+	for _, c := range DefaultColumns {
+		if !seen[c.Field] {
+			found[drift{kind: driftMissing, column: c.Field}]++
+		}
+	}
 
 	return assignment, nil
 }
 
+// assignmentFields set each field from a cell value, reporting whether the
+// value had a usable type.
+var assignmentFields = map[string]func(*model.Assignment, interface{}) bool{
+	"id":        intField(func(a *model.Assignment) *int { return &a.ID }),
+	"unit":      intField(func(a *model.Assignment) *int { return &a.Unit }),
+	"title":     stringField(func(a *model.Assignment) *string { return &a.Title }),
+	"type":      textField(func(a *model.Assignment, s string) { a.Type = model.ParseAssignmentType(s) }),
+	"progress":  intField(func(a *model.Assignment) *int { return &a.Progress }),
+	"due":       stringField(func(a *model.Assignment) *string { return &a.Due }),
+	"completed": stringField(func(a *model.Assignment) *string { return &a.Completed }),
+	"score":     intField(func(a *model.Assignment) *int { return &a.Score }),
+	"status":    textField(func(a *model.Assignment, s string) { a.Status = model.ParseStatus(s) }),
+}
+
+func intField(field func(*model.Assignment) *int) func(*model.Assignment, interface{}) bool {
+	return func(a *model.Assignment, v interface{}) bool {
+		if v == nil {
+			return true
+		}
+
+		n, ok := number(v)
+		if ok {
+			*field(a) = int(n)
+		}
+
+		return ok
+	}
+}
+
+func stringField(field func(*model.Assignment) *string) func(*model.Assignment, interface{}) bool {
+	return textField(func(a *model.Assignment, s string) { *field(a) = s })
+}
+
+func textField(set func(*model.Assignment, string)) func(*model.Assignment, interface{}) bool {
+	return func(a *model.Assignment, v interface{}) bool {
+		switch s := v.(type) {
+		case string:
+			set(a, s)
+		case nil:
+		default:
+			return false
+		}
+
+		return true
+	}
+}
+
+// number accepts JSON numbers and numbers sent as strings.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
 type assignmentResponseHelper struct {
 	Page        int
 	Total       int
 	Records     int
 	Assignments []*model.Assignment
+
+	// columns lays out the cells unless the response describes them, and
+	// drift records how the rows differed from them.
+	columns []Column
+	drift   drifts
 }
 
 func (a *assignmentResponseHelper) UnmarshalJSON(b []byte) error {
 	type responseType struct {
-		Page        interface{} `json:"page"`
-		Total       int         `json:"total"`
-		Records     int         `json:"records"`
-		Assignments interface{} `json:"rows"`
+		Page        interface{}  `json:"page"`
+		Total       int          `json:"total"`
+		Records     int          `json:"records"`
+		Assignments interface{}  `json:"rows"`
+		ColModel    []gridColumn `json:"colModel"`
 	}
 
 	r := responseType{} // nolint: exhaustivestruct,varnamelen
@@ -146,6 +255,16 @@ func (a *assignmentResponseHelper) UnmarshalJSON(b []byte) error {
 
 	a.Total = r.Total
 	a.Records = r.Records
+	a.drift = drifts{}
+
+	columns := a.columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+
+	if len(r.ColModel) > 0 {
+		columns = fromColModel(r.ColModel, columns)
+	}
 
 	switch v := r.Page.(type) { // nolint: varnamelen
 	case float64:
@@ -180,7 +299,7 @@ func (a *assignmentResponseHelper) UnmarshalJSON(b []byte) error {
 		for _, v := range rows {
 			switch cell := v.(type) {
 			case map[string]interface{}:
-				assignment, err := ToAssignment(cell)
+				assignment, err := toAssignment(cell, columns, a.drift)
 				if err != nil {
 					return err
 				}
@@ -209,3 +328,29 @@ func (a *assignmentResponseHelper) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// gridColumn is a column of a jqGrid colModel.
+type gridColumn struct {
+	Name string `json:"name"`
+}
+
+// fromColModel lays out the cells by the grid's own column model, keeping the
+// field mapping of any configured column of the same name.
+func fromColModel(colModel []gridColumn, configured []Column) []Column {
+	fields := map[string]string{}
+	for _, c := range configured {
+		fields[c.Name] = c.Field
+	}
+
+	columns := make([]Column, 0, len(colModel))
+	for _, m := range colModel {
+		field, ok := fields[m.Name]
+		if !ok {
+			field = strings.ToLower(m.Name)
+		}
+
+		columns = append(columns, Column{Name: m.Name, Field: field})
+	}
+
+	return columns
+}
@@ -0,0 +1,192 @@
+package collect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+func TestToAssignment(t *testing.T) {
+	tests := map[string]struct {
+		row   string
+		want  *model.Assignment
+		drift drifts
+	}{
+		"cells": {
+			row: `{"id": 3, "cell": [3, 1, "Fractions", "Quiz", 100, "2022-09-01", "2022-08-31", 90, "Graded"]}`,
+			want: &model.Assignment{
+				ID: 3, Unit: 1, Title: "Fractions", Type: model.TypeQuiz, Progress: 100,
+				Due: "2022-09-01", Completed: "2022-08-31", Score: 90, Status: model.StatusGraded,
+			},
+			drift: drifts{},
+		},
+		"named cells": {
+			row:   `{"id": "3", "cell": {"id": 3, "unit": "1", "title": "Fractions", "type": "Quiz", "progress": 0, "due": null, "completed": null, "score": null, "status": "Not Started"}}`,
+			want:  &model.Assignment{ID: 3, Unit: 1, Title: "Fractions", Type: model.TypeQuiz, Status: model.StatusNotStarted},
+			drift: drifts{},
+		},
+		"id cell differs": {
+			row: `{"id": 3, "cell": [4, 1, "Fractions", "Quiz", 100, "2022-09-01", "2022-08-31", 90, "Graded"]}`,
+			want: &model.Assignment{
+				ID: 3, Unit: 1, Title: "Fractions", Type: model.TypeQuiz, Progress: 100,
+				Due: "2022-09-01", Completed: "2022-08-31", Score: 90, Status: model.StatusGraded,
+				Extra: map[string]interface{}{"id": float64(4)},
+			},
+			drift: drifts{{kind: driftID, column: "id"}: 1},
+		},
+		"extra and missing": {
+			row: `{"id": 3, "cell": [3, 1, "Fractions", "Quiz", 100, "2022-09-01", "2022-08-31", 90, "Graded", "Mr. Rivera"]}`,
+			want: &model.Assignment{
+				ID: 3, Unit: 1, Title: "Fractions", Type: model.TypeQuiz, Progress: 100,
+				Due: "2022-09-01", Completed: "2022-08-31", Score: 90, Status: model.StatusGraded,
+				Extra: map[string]interface{}{"column10": "Mr. Rivera"},
+			},
+			drift: drifts{{kind: driftExtra, column: "column10"}: 1},
+		},
+		"wrong type": {
+			row: `{"id": 3, "cell": [3, 1, "Fractions", "Quiz", "most", "2022-09-01", "2022-08-31", 90, "Graded"]}`,
+			want: &model.Assignment{
+				ID: 3, Unit: 1, Title: "Fractions", Type: model.TypeQuiz,
+				Due: "2022-09-01", Completed: "2022-08-31", Score: 90, Status: model.StatusGraded,
+				Extra: map[string]interface{}{"progress": "most"},
+			},
+			drift: drifts{{kind: driftType, column: "progress"}: 1, {kind: driftMissing, column: "progress"}: 1},
+		},
+	}
+
+	for name, tc := range tests {
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(tc.row), &raw); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		found := drifts{}
+
+		got, err := toAssignment(raw, DefaultColumns, found)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, tc.want)
+		}
+
+		if !reflect.DeepEqual(found, tc.drift) {
+			t.Errorf("%s: drift %v, want %v", name, found, tc.drift)
+		}
+	}
+}
+
+func TestToAssignmentWithoutID(t *testing.T) {
+	_, err := toAssignment(map[string]interface{}{"cell": []interface{}{}}, DefaultColumns, drifts{})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("err = %v, want ErrValidation", err)
+	}
+}
+
+// pager serves pages of assignments with the given ids, claiming records
+// assignments over total pages. A stuck pager serves the first page for
+// every request.
+type pager struct {
+	pages          [][]int
+	records, total int
+	stuck          bool
+
+	requested []int
+}
+
+func (p *pager) students(context.Context) ([]*model.Student, error) { return nil, nil }
+
+func (p *pager) courses(context.Context, *model.Student) ([]*model.Course, error) { return nil, nil }
+
+func (p *pager) courseDetails(context.Context, *model.Course) (*details, error) { return nil, nil }
+
+func (p *pager) assignmentPage(
+	_ context.Context, _ *model.Student, _ *model.Course, page int,
+) (*assignmentResponseHelper, error) {
+	p.requested = append(p.requested, page)
+
+	if p.stuck {
+		page = 1
+	}
+
+	helper := &assignmentResponseHelper{Page: page, Total: p.total, Records: p.records}
+
+	if page <= len(p.pages) {
+		for _, id := range p.pages[page-1] {
+			helper.Assignments = append(helper.Assignments, &model.Assignment{ID: id})
+		}
+	}
+
+	return helper, nil
+}
+
+func TestAssignmentPages(t *testing.T) {
+	tests := map[string]struct {
+		p         pager
+		requested []int
+		ids       []int
+	}{
+		"three pages": {
+			p:         pager{pages: [][]int{{1, 2}, {3, 4}, {5}}, records: 5, total: 3},
+			requested: []int{1, 2, 3},
+			ids:       []int{1, 2, 3, 4, 5},
+		},
+		"repeated rows": {
+			p:         pager{pages: [][]int{{1, 2}, {2, 3}}, records: 3, total: 2},
+			requested: []int{1, 2},
+			ids:       []int{1, 2, 3},
+		},
+		"more pages than claimed": {
+			p:         pager{pages: [][]int{{1, 2}, {3, 4}, {5, 6}}, records: 10, total: 2},
+			requested: []int{1, 2},
+		},
+		"fewer records than claimed": {
+			p:         pager{pages: [][]int{{1, 2}, {3}}, records: 6, total: 5},
+			requested: []int{1, 2, 3},
+		},
+		"more records than claimed": {
+			p:         pager{pages: [][]int{{1, 2}, {3, 4}, {5}}, records: 3, total: 3},
+			requested: []int{1, 2},
+		},
+		"same page every time": {
+			p:         pager{pages: [][]int{{1, 2}, {3, 4}}, records: 4, total: 2, stuck: true},
+			requested: []int{1, 2},
+		},
+	}
+
+	for name, tc := range tests {
+		got, err := assignments(context.Background(), &tc.p, &model.Student{ID: 1}, &model.Course{ID: 7})
+
+		if !reflect.DeepEqual(tc.p.requested, tc.requested) {
+			t.Errorf("%s: requested pages %v, want %v", name, tc.p.requested, tc.requested)
+		}
+
+		if tc.ids == nil {
+			if !errors.Is(err, ErrValidation) {
+				t.Errorf("%s: err = %v, want ErrValidation", name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		var ids []int
+		for _, a := range got {
+			ids = append(ids, a.ID)
+		}
+
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: assignments %v, want %v", name, ids, tc.ids)
+		}
+	}
+}
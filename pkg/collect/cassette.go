@@ -0,0 +1,309 @@
+package collect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Record saves every exchange with the portal as a file in dir, with
+// credentials and cookies scrubbed, for later use with Replay. Recording into
+// a directory that already holds exchanges adds to them, numbered after the
+// last one.
+func Record(dir string) Option {
+	return func(s *Session) { s.transport = &recorder{dir: dir, next: http.DefaultTransport} }
+}
+
+// Replay answers every request from the exchanges recorded in dir, without
+// touching the network.
+func Replay(dir string) Option {
+	return func(s *Session) { s.transport = &replayer{dir: dir} }
+}
+
+// exchange is a single recorded request and its response.
+type exchange struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Form   url.Values  `json:"form,omitempty"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// key identifies equivalent requests, ignoring the host, credentials and the
+// cache busting parameters the portal expects.
+func (e *exchange) key() (string, error) {
+	u, err := url.Parse(scrubURL(e.URL))
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	for _, values := range []url.Values{u.Query(), e.Form} {
+		for k, v := range values {
+			switch {
+			case k == "_", k == "nd", scrubbedForm[k]:
+				continue
+			}
+
+			params[k] = append(params[k], v...)
+		}
+	}
+
+	return fmt.Sprintf("%s %s?%s", e.Method, u.Path, params.Encode()), nil
+}
+
+var (
+	scrubbedForm    = map[string]bool{"j_username": true, "j_password": true}
+	scrubbedHeaders = []string{"Set-Cookie", "Cookie", "Authorization"}
+	sessionIDParam  = regexp.MustCompile(`(?i);jsessionid=[^?#/]*`)
+)
+
+// scrubURL removes a session id carried in the path of link.
+func scrubURL(link string) string { return sessionIDParam.ReplaceAllString(link, "") }
+
+const redacted = "REDACTED"
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	once     sync.Once
+	errStart error
+	seq      uint32
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	form, err := requestForm(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	for k := range form {
+		if scrubbedForm[k] {
+			form[k] = []string{redacted}
+		}
+	}
+
+	header := resp.Header.Clone()
+	for _, h := range scrubbedHeaders {
+		header.Del(h)
+	}
+
+	header.Del("Content-Length")
+
+	if location := header.Get("Location"); location != "" {
+		header.Set("Location", scrubURL(location))
+	}
+
+	ex := &exchange{
+		Method: req.Method,
+		URL:    scrubURL(req.URL.String()),
+		Form:   form,
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   string(body),
+	}
+
+	if err = r.save(ex); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// start creates the directory and numbers the exchanges after those already
+// recorded in it.
+func (r *recorder) start() {
+	if r.errStart = os.MkdirAll(r.dir, 0o755); r.errStart != nil {
+		return
+	}
+
+	names, err := filepath.Glob(filepath.Join(r.dir, "*.json"))
+	if err != nil {
+		r.errStart = err
+		return
+	}
+
+	for _, name := range names {
+		var seq uint32
+		if _, err = fmt.Sscanf(filepath.Base(name), "%d-", &seq); err == nil && seq > r.seq {
+			r.seq = seq
+		}
+	}
+}
+
+func (r *recorder) save(ex *exchange) error {
+	if r.once.Do(r.start); r.errStart != nil {
+		return r.errStart
+	}
+
+	var data bytes.Buffer
+
+	enc := json.NewEncoder(&data)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(ex); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%04d-%s-%s.json", atomic.AddUint32(&r.seq, 1), strings.ToLower(ex.Method), endpoint(ex.URL))
+
+	return os.WriteFile(filepath.Join(r.dir, name), data.Bytes(), 0o600)
+}
+
+type replayer struct {
+	dir string
+
+	once    sync.Once
+	errLoad error
+
+	mu    sync.Mutex
+	tapes map[string][]*exchange
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.once.Do(r.load); r.errLoad != nil {
+		return nil, r.errLoad
+	}
+
+	form, err := requestForm(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := (&exchange{Method: req.Method, URL: req.URL.String(), Form: form}).key()
+	if err != nil {
+		return nil, err
+	}
+
+	ex := r.next(key)
+	if ex == nil {
+		return nil, fmt.Errorf("no recorded exchange for %s in %q", key, r.dir)
+	}
+
+	header := ex.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(ex.Body)),
+		ContentLength: int64(len(ex.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next returns the recorded exchanges for key in order, repeating the last
+// one once they are used up.
+func (r *replayer) next(key string) *exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tape := r.tapes[key]
+	switch len(tape) {
+	case 0:
+		return nil
+	case 1:
+		return tape[0]
+	default:
+		r.tapes[key] = tape[1:]
+		return tape[0]
+	}
+}
+
+func (r *replayer) load() {
+	names, err := filepath.Glob(filepath.Join(r.dir, "*.json"))
+	if err != nil {
+		r.errLoad = err
+		return
+	}
+
+	if len(names) == 0 {
+		r.errLoad = fmt.Errorf("no recorded exchanges in %q", r.dir)
+		return
+	}
+
+	sort.Strings(names)
+
+	r.tapes = map[string][]*exchange{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			r.errLoad = err
+			return
+		}
+
+		ex := &exchange{}
+		if err = json.Unmarshal(data, ex); err != nil {
+			r.errLoad = fmt.Errorf("%s: %w", name, err)
+			return
+		}
+
+		key, err := ex.key()
+		if err != nil {
+			r.errLoad = fmt.Errorf("%s: %w", name, err)
+			return
+		}
+
+		r.tapes[key] = append(r.tapes[key], ex)
+	}
+}
+
+// requestForm reads the form encoded body of req, if any, leaving the body in
+// place for the request to be sent.
+func requestForm(req *http.Request) (url.Values, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return url.ParseQuery(string(body))
+}
+
+func endpoint(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+		return "root"
+	}
+
+	return path.Base(u.Path)
+}
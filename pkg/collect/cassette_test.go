@@ -0,0 +1,64 @@
+package collect
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordAddsToCassette(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(writer, request.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	record := func(paths ...string) {
+		t.Helper()
+
+		client := &http.Client{Transport: &recorder{dir: dir, next: http.DefaultTransport}}
+		for _, p := range paths {
+			resp, err := client.Get(srv.URL + p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+		}
+	}
+
+	record("/", "/owsoo/parent/populateStudents")
+
+	// A later recording into the same directory carries on the numbering
+	// rather than overwriting the first.
+	record("/owsoo/parent/populateCourses")
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+
+	want := []string{"0001-get-root.json", "0002-get-populateStudents.json", "0003-get-populateCourses.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("recorded %v, want %v", names, want)
+	}
+
+	r := &replayer{dir: dir}
+	r.load()
+
+	if r.errLoad != nil {
+		t.Fatal(r.errLoad)
+	}
+
+	if len(r.tapes) != 3 {
+		t.Errorf("replaying found %d requests, want 3", len(r.tapes))
+	}
+}
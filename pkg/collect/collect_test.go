@@ -0,0 +1,340 @@
+package collect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// The expired cassette records a portal whose session expires on the first
+// course listing and whose course details fail once with a 503.
+const expiredCassette = "testdata/expired"
+
+func replaySession(retries int) *Session {
+	return NewSession("http://portal.test", "parent", "secret",
+		Replay(expiredCassette), Retries(retries), Backoff(time.Millisecond), RateLimit(0))
+}
+
+func TestGatherReplay(t *testing.T) {
+	asOf := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	s := replaySession(3)
+
+	data, err := gather(context.Background(), s, asOf, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every scripted exchange was used: the relogin and the retry happened.
+	for key, tape := range s.transport.(*replayer).tapes {
+		if len(tape) > 1 {
+			t.Errorf("%d exchanges left unused for %s", len(tape)-1, key)
+		}
+	}
+
+	if len(data.Failures) > 0 {
+		t.Fatalf("failures = %v", data.Failures)
+	}
+
+	want := map[int]*model.Student{101: {ID: 101, DisplayName: "Anna Example", Courses: map[int]*model.Course{
+		5001: {
+			ID: 5001, StudentID: 101, Title: "Algebra I", Subject: "Math", Teacher: "Mr. Rivera",
+			Start: "2022-08-15", End: "2023-05-26", Progress: 50, Score: 95,
+			Assignments: map[int]*model.Assignment{
+				1: {
+					ID: 1, CourseID: 5001, StudentID: 101, Unit: 1, Title: "Variables", Type: model.TypeLesson,
+					Progress: 100, Due: "2022-08-20", Completed: "2022-08-19", Score: 95, Status: model.StatusGraded,
+				},
+				2: {
+					ID: 2, CourseID: 5001, StudentID: 101, Unit: 1, Title: "Expressions", Type: model.TypeQuiz,
+					Due: "2022-09-10", Status: model.StatusNotStarted,
+				},
+			},
+		},
+	}}}
+
+	if !data.AsOf.Equal(asOf) {
+		t.Errorf("as of %s, want %s", data.AsOf, asOf)
+	}
+
+	if !reflect.DeepEqual(data.Students, want) {
+		t.Errorf("students =\n%s\nwant\n%s", dump(data.Students), dump(want))
+	}
+}
+
+func TestGatherReplayWithoutRetries(t *testing.T) {
+	data, err := gather(context.Background(), replaySession(0), time.Now(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Failures) != 1 {
+		t.Fatalf("failures = %v, want one", data.Failures)
+	}
+
+	f := data.Failures[0]
+	if f.StudentID != 101 || f.Stage != model.StageCourses || f.Message != ErrSessionExpired.Error() {
+		t.Errorf("failure = %+v", f)
+	}
+
+	if s := data.Students[101]; s == nil || len(s.Courses) != 0 {
+		t.Errorf("student = %+v, want no courses", s)
+	}
+}
+
+func TestReprocessArchive(t *testing.T) {
+	archive := DirArchive(t.TempDir())
+
+	s := NewSession("http://portal.test", "parent", "secret",
+		Replay(expiredCassette), ArchiveTo(archive), Backoff(time.Millisecond), RateLimit(0))
+
+	data, err := s.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Failures) > 0 {
+		t.Fatalf("failures = %v", data.Failures)
+	}
+
+	snapshots, err := archive.RawSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 || !snapshots[0].Equal(data.AsOf) {
+		t.Fatalf("archived snapshots = %v, want %s", snapshots, data.AsOf)
+	}
+
+	// Reprocessing needs only the archive, not the portal.
+	offline := NewSession("http://portal.invalid", "parent", "secret", ArchiveTo(archive))
+
+	again, err := offline.Reprocess(context.Background(), snapshots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !again.AsOf.Equal(data.AsOf) {
+		t.Errorf("reprocessed as of %s, want %s", again.AsOf, data.AsOf)
+	}
+
+	again.AsOf = data.AsOf
+	if !reflect.DeepEqual(again, data) {
+		t.Errorf("reprocessed =\n%s\nwant\n%s", dump(again.Students), dump(data.Students))
+	}
+}
+
+// snapshots is a history holding only the times of its snapshots.
+type snapshots []time.Time
+
+func (s snapshots) Snapshots() ([]time.Time, error) { return s, nil }
+
+func (s snapshots) DataAsOf(when time.Time) (model.Data, error) {
+	return model.Data{AsOf: when}, model.ErrNoSnapshot
+}
+
+func TestPruneArchive(t *testing.T) {
+	archive := DirArchive(t.TempDir())
+
+	s := NewSession("http://portal.test", "parent", "secret",
+		Replay(expiredCassette), ArchiveTo(archive), Backoff(time.Millisecond), RateLimit(0))
+
+	data, err := s.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stored snapshot keeps its responses.
+	if err = s.PruneArchive(snapshots{data.AsOf}); err != nil {
+		t.Fatal(err)
+	}
+
+	if archived, err := archive.RawSnapshots(); err != nil || len(archived) != 1 {
+		t.Fatalf("archived snapshots = %v, %v, want %s", archived, err, data.AsOf)
+	}
+
+	// One left out as unchanged does not.
+	if err = s.PruneArchive(snapshots{data.AsOf.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if archived, err := archive.RawSnapshots(); err != nil || len(archived) != 0 {
+		t.Fatalf("archived snapshots = %v, %v, want none", archived, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"expired":   {ErrSessionExpired, true},
+		"server":    {&statusError{code: 503, err: errTest}, true},
+		"canceled":  {context.Canceled, false},
+		"permanent": {errTest, false},
+	}
+
+	for name, tc := range tests {
+		if got := retryable(tc.err); got != tc.want {
+			t.Errorf("%s: retryable = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+var errTest = errors.New("test")
+
+func TestLoginRetries(t *testing.T) {
+	var logins int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/" {
+			atomic.AddInt32(&logins, 1)
+		}
+
+		http.Error(writer, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s := NewSession(srv.URL, "parent", "secret", Retries(2), Backoff(time.Millisecond), RateLimit(0))
+
+	// Logging in shares the budget of the request it is made for: one
+	// attempt and two retries.
+	if _, err := s.Students(); err == nil {
+		t.Fatal("listed students from a portal that is down")
+	}
+
+	if n := atomic.LoadInt32(&logins); n != 3 {
+		t.Errorf("tried to log in %d times, want 3", n)
+	}
+
+	// The outage is not held against the next request.
+	if _, err := s.Students(); err == nil {
+		t.Fatal("listed students from a portal that is down")
+	}
+
+	if n := atomic.LoadInt32(&logins); n != 6 {
+		t.Errorf("tried to log in %d times, want 6", n)
+	}
+}
+
+func dump(students map[int]*model.Student) string {
+	var out strings.Builder
+
+	for _, s := range students {
+		fmt.Fprintf(&out, "%+v\n", *s)
+
+		for _, c := range s.SortedCourses() {
+			fmt.Fprintf(&out, "  %+v\n", *c)
+
+			for _, a := range c.SortedAssignments() {
+				fmt.Fprintf(&out, "    %+v\n", *a)
+			}
+		}
+	}
+
+	return out.String()
+}
+
+func TestCourseDetailsKeepListing(t *testing.T) {
+	d, err := parseCourseDetails([]byte(`{"id": 9, "title": "Renamed", "courseTitle": "Renamed", "assignments": null,
+		"courseSubject": "Math", "teacher": "Mr. Rivera", "currentProgress": 50, "currentScore": 95}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	course := &model.Course{ID: 5001, StudentID: 101, Title: "Algebra I", Assignments: map[int]*model.Assignment{}}
+	d.apply(course)
+
+	want := &model.Course{
+		ID: 5001, StudentID: 101, Title: "Algebra I", Subject: "Math", Teacher: "Mr. Rivera",
+		Progress: 50, Score: 95, Assignments: map[int]*model.Assignment{},
+	}
+
+	if !reflect.DeepEqual(course, want) {
+		t.Errorf("course = %+v, want %+v", course, want)
+	}
+}
+
+func TestMetricsOffDefaultRegistry(t *testing.T) {
+	for name, c := range map[string]prometheus.Collector{
+		"column drift": columnDrift, "unknown values": unknownValues, "snapshot duration": snapshotDuration,
+		"request duration": requestDuration, "responses": responses, "logins": logins,
+		"parse errors": parseErrors, "collected": collected,
+	} {
+		if prometheus.Unregister(c) {
+			t.Errorf("%s was on the default registry", name)
+		}
+	}
+}
+
+func TestReportUnknown(t *testing.T) {
+	// unknown returns the counts of unknown values by field, failing on any
+	// other label.
+	unknown := func() map[string]float64 {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := map[string]float64{}
+
+		for _, family := range families {
+			if family.GetName() != "ignitia_collect_unknown_values_total" {
+				continue
+			}
+
+			for _, m := range family.GetMetric() {
+				if labels := m.GetLabel(); len(labels) != 1 || labels[0].GetName() != "field" {
+					t.Fatalf("labels = %v, want only field", labels)
+				}
+
+				counts[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+			}
+		}
+
+		return counts
+	}
+
+	var logged strings.Builder
+
+	s := NewSession("http://portal.test", "parent", "secret")
+	s.logger = log.New(&logged, "", 0)
+
+	before := unknown()
+
+	s.reportUnknown([]*model.Assignment{
+		{ID: 1, Type: model.TypeLesson, Status: "Archived"},
+		{ID: 2, Type: "Worksheet", Status: "Archived"},
+		{ID: 3, Type: model.TypeQuiz, Status: "Excused"},
+	})
+
+	after := unknown()
+
+	if got := after["status"] - before["status"]; got != 3 {
+		t.Errorf("unknown statuses counted %v, want 3", got)
+	}
+
+	if got := after["type"] - before["type"]; got != 1 {
+		t.Errorf("unknown types counted %v, want 1", got)
+	}
+
+	want := `warning: unknown assignment status "Archived"
+warning: unknown assignment type "Worksheet"
+warning: unknown assignment status "Excused"
+`
+	if logged.String() != want {
+		t.Errorf("logged %q, want %q", logged.String(), want)
+	}
+}
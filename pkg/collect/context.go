@@ -0,0 +1,64 @@
+package collect
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// callHeader tags each request with the call it belongs to, since colly
+// requests carry no context of their own. It is removed before sending.
+const callHeader = "X-Ignitia-Call"
+
+// calls is a transport that applies the context of the call each request was
+// tagged with, so that cancelling a call abandons its requests in flight.
+type calls struct {
+	next http.RoundTripper
+
+	seq    uint64
+	active sync.Map
+}
+
+// track registers ctx and returns the id to tag its requests with, and a
+// function to release it once the call is done.
+func (c *calls) track(ctx context.Context) (string, func()) {
+	id := strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 10)
+	c.active.Store(id, ctx)
+
+	return id, func() { c.active.Delete(id) }
+}
+
+func (c *calls) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := req.Header.Get(callHeader); id != "" {
+		ctx := req.Context()
+		if v, ok := c.active.Load(id); ok {
+			ctx = v.(context.Context)
+		}
+
+		req = req.Clone(ctx)
+		req.Header.Del(callHeader)
+	}
+
+	return observe(c.next, req)
+}
+
+// observe sends req through next, recording its latency and status.
+func observe(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	name, start := endpoint(scrubURL(req.URL.String())), time.Now()
+
+	resp, err := next.RoundTrip(req)
+
+	requestDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		responses.WithLabelValues(name, "error").Inc()
+		return nil, err
+	}
+
+	responses.WithLabelValues(name, strconv.Itoa(resp.StatusCode)).Inc()
+
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+package collect
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CookieKeySize is the size in bytes of the key the cookies are encrypted
+// with.
+const CookieKeySize = 32
+
+// CookieStore keeps the encrypted cookies of a portal session between runs.
+// LoadCookies returns nil when nothing has been saved yet.
+type CookieStore interface {
+	LoadCookies() ([]byte, error)
+	SaveCookies([]byte) error
+}
+
+// FileCookies keeps the cookies in the file at path.
+func FileCookies(path string) CookieStore { return fileCookies(path) }
+
+type fileCookies string
+
+func (f fileCookies) LoadCookies() ([]byte, error) {
+	b, err := os.ReadFile(string(f))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return b, err
+}
+
+func (f fileCookies) SaveCookies(b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(string(f), b, 0o600)
+}
+
+// ParseCookieKey decodes a base64 encoded key of CookieKeySize random bytes,
+// such as the output of `openssl rand -base64 32`.
+func ParseCookieKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		key, err := enc.DecodeString(s)
+		if err != nil {
+			continue
+		}
+
+		if len(key) != CookieKeySize {
+			return nil, fmt.Errorf("cookie key must be %d bytes, got %d", CookieKeySize, len(key))
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("cookie key must be base64 encoded")
+}
+
+// Cookies saves the session's cookies in store, encrypted with key, and
+// reuses them on the next run, logging in only once they are rejected. The key
+// must be CookieKeySize bytes, as returned by ParseCookieKey.
+func Cookies(store CookieStore, key []byte) Option {
+	return func(s *Session) {
+		s.cookies = store
+		s.cookieKey = append([]byte(nil), key...)
+	}
+}
+
+// savedCookie is a cookie as kept in the store. The jar only reveals the name
+// and value of each cookie, so they are restored as host cookies for the
+// whole portal.
+type savedCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cookieURLs are where the portal sets the cookies worth keeping.
+func (s *Session) cookieURLs() []*url.URL {
+	var urls []*url.URL
+
+	for _, link := range []string{s.baseURL + "/", s.baseURL + "/owsoo/parent/"} {
+		if u, err := url.Parse(link); err == nil {
+			urls = append(urls, u)
+		}
+	}
+
+	return urls
+}
+
+// restoreCookies fills jar with the saved cookies, reporting whether there
+// were any.
+func (s *Session) restoreCookies(jar http.CookieJar) bool {
+	if s.cookies == nil {
+		return false
+	}
+
+	sealed, err := s.cookies.LoadCookies()
+	if err != nil || len(sealed) == 0 {
+		if err != nil {
+			s.logger.Printf("warning: loading cookies: %v\n", err)
+		}
+
+		return false
+	}
+
+	b, err := openCookies(s.cookieKey, s.cookieContext(), sealed)
+	if err != nil {
+		s.logger.Printf("warning: loading cookies: %v\n", err)
+		return false
+	}
+
+	var saved []savedCookie
+	if err = json.Unmarshal(b, &saved); err != nil || len(saved) == 0 {
+		return false
+	}
+
+	cookies := make([]*http.Cookie, 0, len(saved))
+	for _, c := range saved {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value, Path: "/"})
+	}
+
+	for _, u := range s.cookieURLs() {
+		jar.SetCookies(u, cookies)
+	}
+
+	return true
+}
+
+// saveCookies stores the cookies in jar for the next run.
+func (s *Session) saveCookies(jar http.CookieJar) {
+	if s.cookies == nil || jar == nil {
+		return
+	}
+
+	var (
+		saved []savedCookie
+		seen  = map[string]bool{}
+	)
+
+	for _, u := range s.cookieURLs() {
+		for _, c := range jar.Cookies(u) {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				saved = append(saved, savedCookie{Name: c.Name, Value: c.Value})
+			}
+		}
+	}
+
+	b, err := json.Marshal(saved)
+	if err != nil {
+		s.logger.Printf("warning: saving cookies: %v\n", err)
+		return
+	}
+
+	sealed, err := sealCookies(s.cookieKey, s.cookieContext(), b)
+	if err == nil {
+		err = s.cookies.SaveCookies(sealed)
+	}
+
+	if err != nil {
+		s.logger.Printf("warning: saving cookies: %v\n", err)
+	}
+}
+
+// cookieContext binds saved cookies to the portal and account they were
+// issued for.
+func (s *Session) cookieContext() []byte { return []byte(s.baseURL + "\x00" + s.username) }
+
+// sealCookies encrypts b with AES-GCM, prefixing the random nonce.
+func sealCookies(key, context, b []byte) ([]byte, error) {
+	aead, err := cookieCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, b, context), nil
+}
+
+func openCookies(key, context, sealed []byte) ([]byte, error) {
+	aead, err := cookieCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("saved cookies are truncated")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, context)
+}
+
+func cookieCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != CookieKeySize {
+		return nil, fmt.Errorf("cookie key must be %d bytes, got %d", CookieKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,60 @@
+package collect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseCookieKey(t *testing.T) {
+	want := bytes.Repeat([]byte{0xfb}, CookieKeySize)
+
+	for _, s := range []string{
+		"+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/s=",
+		"+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/v7+/s",
+		"-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_s=\n",
+	} {
+		if key, err := ParseCookieKey(s); err != nil || !bytes.Equal(key, want) {
+			t.Errorf("%q: key %x, err %v", s, key, err)
+		}
+	}
+
+	tests := map[string]string{
+		"not a key!":               "must be base64",
+		"secret":                   "must be 32 bytes, got 4",
+		"c2VjcmV0":                 "must be 32 bytes, got 6",
+		strings.Repeat("QUFB", 12): "must be 32 bytes, got 36",
+	}
+
+	for s, want := range tests {
+		if _, err := ParseCookieKey(s); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err %v, want %q", s, err, want)
+		}
+	}
+}
+
+func TestSealCookies(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, CookieKeySize)
+	context := []byte("http://portal.test\x00parent")
+
+	sealed, err := sealCookies(key, context, []byte("cookies"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := openCookies(key, context, sealed); err != nil || string(b) != "cookies" {
+		t.Errorf("opened %q, err %v", b, err)
+	}
+
+	if _, err := openCookies(key, []byte("http://portal.test\x00other"), sealed); err == nil {
+		t.Error("opened cookies saved for another account")
+	}
+
+	if _, err := openCookies(bytes.Repeat([]byte{2}, CookieKeySize), context, sealed); err == nil {
+		t.Error("opened cookies with another key")
+	}
+
+	if _, err := sealCookies(key[:16], context, []byte("cookies")); err == nil {
+		t.Error("sealed cookies with a short key")
+	}
+}
@@ -0,0 +1,452 @@
+// Package fake serves a stand-in for the Ignitia parent portal, backed by a
+// fixture dataset, for exercising collect.Session without the real site.
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// AuthCookie is the portal's authentication cookie.
+const AuthCookie = "f5avrbbbbbbbbbbbbbbbb"
+
+// Option represents a function that can modify a portal.
+type Option func(*Portal)
+
+// Credentials sets the only username and password that may log in. Without
+// it any credentials are accepted.
+func Credentials(username, password string) Option {
+	return func(p *Portal) { p.username, p.password = username, password }
+}
+
+// LoginError rejects every login attempt.
+func LoginError() Option { return func(p *Portal) { p.loginError = true } }
+
+// ExpireAfter ends each session after it has served n data requests, after
+// which the login page is served in place of data.
+func ExpireAfter(n int) Option { return func(p *Portal) { p.expireAfter = n } }
+
+// EmptyRows answers every assignment listing with an empty map for rows, as
+// the portal does for courses without assignments.
+func EmptyRows() Option { return func(p *Portal) { p.emptyRows = true } }
+
+// Latency delays every data request by d.
+func Latency(d time.Duration) Option { return func(p *Portal) { p.latency = d } }
+
+// Portal is an http.Handler imitating the parent portal endpoints described
+// in openapi.json.
+type Portal struct {
+	data model.Data
+
+	username    string
+	password    string
+	loginError  bool
+	expireAfter int
+	emptyRows   bool
+	latency     time.Duration
+
+	mux http.Handler
+
+	mu       sync.Mutex
+	sessions map[string]int
+}
+
+// New returns a Portal serving data.
+func New(data model.Data, opts ...Option) *Portal {
+	p := &Portal{data: data, sessions: map[string]int{}}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.home)
+	mux.HandleFunc("/j_security_check", p.login)
+	mux.HandleFunc("/owsoo/parent/populateStudents", p.authorized(p.students))
+	mux.HandleFunc("/owsoo/parent/populateCourses", p.authorized(p.courses))
+	mux.HandleFunc("/owsoo/parent/populateCourseDetails", p.authorized(p.courseDetails))
+	mux.HandleFunc("/owsoo/parent/listAssignmentsByCourse", p.authorized(p.assignments))
+	p.mux = mux
+
+	return p
+}
+
+func (p *Portal) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	p.mux.ServeHTTP(writer, request)
+}
+
+func (p *Portal) home(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+		return
+	}
+
+	if _, ok := p.session(request); ok {
+		writeHTML(writer, homePage)
+		return
+	}
+
+	writeHTML(writer, loginPage)
+}
+
+func (p *Portal) login(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, password := request.FormValue("j_username"), request.FormValue("j_password")
+	if p.loginError || (p.username != "" && (username != p.username || password != p.password)) {
+		writeHTML(writer, loginErrorPage)
+		return
+	}
+
+	token := newToken()
+
+	p.mu.Lock()
+	p.sessions[token] = 0
+	p.mu.Unlock()
+
+	http.SetCookie(writer, &http.Cookie{Name: AuthCookie, Value: token, Path: "/", HttpOnly: true})
+	http.Redirect(writer, request, "/", http.StatusFound)
+}
+
+// ExpireSessions ends every session, as restarting the portal would.
+func (p *Portal) ExpireSessions() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sessions = map[string]int{}
+}
+
+// session returns the token of the request's live session, if any.
+func (p *Portal) session(request *http.Request) (string, bool) {
+	cookie, err := request.Cookie(AuthCookie)
+	if err != nil {
+		return "", false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.sessions[cookie.Value]
+
+	return cookie.Value, ok
+}
+
+// authorized serves the login page in place of data once a session has
+// expired, the way the real portal does.
+func (p *Portal) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(p.latency)
+
+		token, ok := p.session(request)
+		if ok {
+			p.mu.Lock()
+			p.sessions[token]++
+			if p.expireAfter > 0 && p.sessions[token] > p.expireAfter {
+				delete(p.sessions, token)
+				ok = false
+			}
+			p.mu.Unlock()
+		}
+
+		if !ok {
+			writeHTML(writer, loginPage)
+			return
+		}
+
+		next(writer, request)
+	}
+}
+
+func (p *Portal) students(writer http.ResponseWriter, _ *http.Request) {
+	type student struct {
+		ID          int    `json:"id"`
+		DisplayName string `json:"displayName"`
+	}
+
+	students := []student{}
+	for _, s := range p.data.SortedStudents() {
+		students = append(students, student{ID: s.ID, DisplayName: s.DisplayName})
+	}
+
+	writeJSON(writer, students)
+}
+
+func (p *Portal) courses(writer http.ResponseWriter, request *http.Request) {
+	type course struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}
+
+	id, err := strconv.Atoi(request.FormValue("student_id"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	courses := []course{}
+	if s, ok := p.data.Students[id]; ok {
+		for _, c := range s.SortedCourses() {
+			courses = append(courses, course{ID: c.ID, Title: c.Title})
+		}
+	}
+
+	writeJSON(writer, courses)
+}
+
+func (p *Portal) courseDetails(writer http.ResponseWriter, request *http.Request) {
+	type details struct {
+		Subject  string  `json:"courseSubject"`
+		Title    string  `json:"courseTitle"`
+		Teacher  string  `json:"teacher"`
+		Start    string  `json:"startDate"`
+		End      string  `json:"endDate"`
+		Progress float64 `json:"currentProgress"`
+		Score    float64 `json:"currentScore"`
+	}
+
+	c := p.course(request.FormValue("enrollment_id"))
+	if c == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	writeJSON(writer, details{
+		Subject:  c.Subject,
+		Title:    c.Title,
+		Teacher:  c.Teacher,
+		Start:    c.Start,
+		End:      c.End,
+		Progress: c.Progress,
+		Score:    c.Score,
+	})
+}
+
+func (p *Portal) assignments(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	c := p.course(request.FormValue("enrollment_id"))
+	if c == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	rows, page := formInt(request, "rows", 1000), formInt(request, "page", 1)
+	if rows < 1 || page < 1 {
+		http.Error(writer, "rows and page must be positive", http.StatusBadRequest)
+		return
+	}
+
+	all := c.SortedAssignments()
+	total := (len(all) + rows - 1) / rows
+
+	if p.emptyRows || len(all) == 0 {
+		writeJSON(writer, map[string]interface{}{"page": "1", "total": 0, "records": 0, "rows": map[string]interface{}{}})
+		return
+	}
+
+	var cells []map[string]interface{}
+	for i := (page - 1) * rows; i < len(all) && i < page*rows; i++ {
+		a := all[i]
+		cells = append(cells, map[string]interface{}{
+			"id": a.ID,
+			"cell": []interface{}{
+				a.ID, a.Unit, a.Title, a.Type, a.Progress,
+				nullable(a.Due), nullable(a.Completed), a.Score, a.Status,
+			},
+		})
+	}
+
+	if cells == nil {
+		cells = []map[string]interface{}{}
+	}
+
+	writeJSON(writer, map[string]interface{}{
+		"page":    strconv.Itoa(page),
+		"total":   total,
+		"records": len(all),
+		"rows":    cells,
+	})
+}
+
+func (p *Portal) course(enrollment string) *model.Course {
+	id, err := strconv.Atoi(enrollment)
+	if err != nil {
+		return nil
+	}
+
+	for _, s := range p.data.Students {
+		if c, ok := s.Courses[id]; ok {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func formInt(request *http.Request, name string, fallback int) int {
+	n, err := strconv.Atoi(request.FormValue(name))
+	if err != nil {
+		return fallback
+	}
+
+	return n
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func writeHTML(writer http.ResponseWriter, page string) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(writer, page)
+}
+
+func writeJSON(writer http.ResponseWriter, v interface{}) {
+	writer.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	if err := json.NewEncoder(writer).Encode(v); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Load reads a fixture dataset from the students of a snapshot in JSON form,
+// such as the output of /report?json=true.
+func Load(path string) (model.Data, error) {
+	var fixture struct {
+		Students map[int]*model.Student `json:"students"`
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	if err = json.Unmarshal(raw, &fixture); err != nil {
+		return model.Data{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return model.Data{Students: fixture.Students, AsOf: time.Now()}, nil
+}
+
+// Demo returns a small dataset with work spread around the current date.
+func Demo() model.Data {
+	day := func(offset int) string { return time.Now().AddDate(0, 0, offset).Format("2006-01-02") }
+
+	course := func(studentID, id int, title, subject, teacher string, assignments ...*model.Assignment) *model.Course {
+		c := &model.Course{
+			ID:          id,
+			StudentID:   studentID,
+			Title:       title,
+			Subject:     subject,
+			Teacher:     teacher,
+			Start:       day(-60),
+			End:         day(120),
+			Assignments: map[int]*model.Assignment{},
+		}
+
+		var scored, score int
+		for _, a := range assignments {
+			a.StudentID, a.CourseID = studentID, id
+			c.Assignments[a.ID] = a
+
+			if a.Score > 0 {
+				scored++
+				score += a.Score
+			}
+		}
+
+		if scored > 0 {
+			c.Score = float64(score) / float64(scored)
+		}
+
+		c.Progress = 100 * float64(scored) / float64(len(assignments))
+
+		return c
+	}
+
+	student := func(id int, name string, courses ...*model.Course) *model.Student {
+		s := &model.Student{ID: id, DisplayName: name, Courses: map[int]*model.Course{}}
+		for _, c := range courses {
+			s.Courses[c.ID] = c
+		}
+
+		return s
+	}
+
+	return model.Data{
+		AsOf: time.Now(),
+		Students: map[int]*model.Student{
+			101: student(101, "Anna Example",
+				course(101, 5001, "Algebra I", "Math", "Mr. Rivera",
+					&model.Assignment{ID: 1, Unit: 1, Title: "Variables", Type: model.TypeLesson, Progress: 100, Due: day(-20), Completed: day(-21), Score: 95, Status: model.StatusGraded},
+					&model.Assignment{ID: 2, Unit: 1, Title: "Unit 1 Quiz", Type: model.TypeQuiz, Progress: 100, Due: day(-14), Completed: day(-14), Score: 82, Status: model.StatusGraded},
+					&model.Assignment{ID: 3, Unit: 2, Title: "Linear Equations", Type: model.TypeLesson, Progress: 40, Due: day(-9), Status: model.StatusInProgress},
+					&model.Assignment{ID: 4, Unit: 2, Title: "Unit 2 Test", Type: model.TypeTest, Due: day(0), Status: model.StatusNotStarted},
+					&model.Assignment{ID: 5, Unit: 3, Title: "Inequalities", Type: model.TypeLesson, Due: day(6), Status: model.StatusNotStarted},
+				),
+				course(101, 5002, "Biology", "Science", "Dr. Chen",
+					&model.Assignment{ID: 11, Unit: 1, Title: "Cells", Type: model.TypeLesson, Progress: 100, Due: day(-3), Completed: day(-4), Score: 88, Status: model.StatusGraded},
+					&model.Assignment{ID: 12, Unit: 1, Title: "Cell Project", Type: model.TypeProject, Due: day(10), Status: model.StatusNotStarted},
+				),
+			),
+			102: student(102, "Ben Example",
+				course(102, 6001, "English 7", "Language Arts", "Ms. Okafor",
+					&model.Assignment{ID: 21, Unit: 1, Title: "Short Stories", Type: model.TypeLesson, Progress: 100, Due: day(-2), Completed: day(-2), Status: model.StatusCompleted},
+					&model.Assignment{ID: 22, Unit: 1, Title: "Vocabulary Quiz", Type: model.TypeQuiz, Due: day(1), Status: model.StatusNotStarted},
+				),
+			),
+		},
+	}
+}
+
+const (
+	loginPage = `<!DOCTYPE html>
+<html><head><title>Login</title></head>
+<body>
+<form id="loginForm" action="/j_security_check" method="post">
+  <input type="text" name="j_username">
+  <input type="password" name="j_password">
+  <input type="submit" value="Log In">
+</form>
+</body></html>
+`
+
+	loginErrorPage = `<!DOCTYPE html>
+<html><head><title>Login</title></head>
+<body>
+<div class="login-error">Invalid username or password.</div>
+</body></html>
+`
+
+	homePage = `<!DOCTYPE html>
+<html><head><title>Parent Portal</title></head>
+<body><p>Welcome.</p></body></html>
+`
+)
@@ -0,0 +1,322 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/collect"
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// counter counts the requests a handler serves by path, and the pages of
+// assignments requested by course.
+type counter struct {
+	next http.Handler
+
+	mu    sync.Mutex
+	paths map[string]int
+	pages map[string][]string
+}
+
+func (c *counter) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	_ = request.ParseForm()
+
+	c.mu.Lock()
+	c.paths[request.URL.Path]++
+	if strings.HasSuffix(request.URL.Path, "/listAssignmentsByCourse") {
+		course := request.FormValue("enrollment_id")
+		c.pages[course] = append(c.pages[course], request.FormValue("page"))
+	}
+	c.mu.Unlock()
+
+	c.next.ServeHTTP(writer, request)
+}
+
+func (c *counter) count(path string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.paths[path]
+}
+
+// serve runs p on a test server, returning its URL and the counter of its
+// requests.
+func serve(t *testing.T, p *Portal) (string, *counter) {
+	t.Helper()
+
+	c := &counter{next: p, paths: map[string]int{}, pages: map[string][]string{}}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+
+	return srv.URL, c
+}
+
+func session(url string, opts ...collect.Option) *collect.Session {
+	opts = append([]collect.Option{collect.RateLimit(0), collect.Backoff(time.Millisecond)}, opts...)
+
+	return collect.NewSession(url, "parent", "secret", opts...)
+}
+
+// same fails unless got holds the students, courses and assignments of want.
+func same(t *testing.T, got, want model.Data) {
+	t.Helper()
+
+	if len(got.Failures) > 0 {
+		t.Fatalf("failures = %v", got.Failures)
+	}
+
+	if !reflect.DeepEqual(got.Students, want.Students) {
+		t.Fatalf("students =\n%s\nwant\n%s", dump(got.Students), dump(want.Students))
+	}
+}
+
+func dump(students map[int]*model.Student) string {
+	b, _ := json.MarshalIndent(students, "", "  ")
+	return string(b)
+}
+
+func TestSessionData(t *testing.T) {
+	demo := Demo()
+	url, requests := serve(t, New(demo, Credentials("parent", "secret")))
+
+	data, err := session(url).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same(t, data, demo)
+
+	if n := requests.count("/j_security_check"); n != 1 {
+		t.Errorf("logged in %d times, want once", n)
+	}
+}
+
+func TestSessionLoginFailure(t *testing.T) {
+	tests := map[string]*Portal{
+		"rejected":          New(Demo(), LoginError()),
+		"wrong credentials": New(Demo(), Credentials("parent", "other")),
+	}
+
+	for name, p := range tests {
+		url, requests := serve(t, p)
+
+		s := session(url)
+		if _, err := s.Data(); err == nil || !strings.Contains(err.Error(), "Invalid username or password") {
+			t.Errorf("%s: err = %v, want the portal's login error", name, err)
+		}
+
+		// The failed login sticks until Reset rather than being repeated.
+		if _, err := s.Data(); err == nil {
+			t.Errorf("%s: second snapshot succeeded", name)
+		}
+
+		if n := requests.count("/j_security_check"); n != 1 {
+			t.Errorf("%s: logged in %d times, want once", name, n)
+		}
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	demo := Demo()
+	url, requests := serve(t, New(demo, ExpireAfter(3)))
+
+	data, err := session(url, collect.Concurrency(1)).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same(t, data, demo)
+
+	if n := requests.count("/j_security_check"); n < 2 {
+		t.Errorf("logged in %d times, want a login for each expired session", n)
+	}
+}
+
+func TestSessionEmptyRows(t *testing.T) {
+	url, _ := serve(t, New(Demo(), EmptyRows()))
+
+	data, err := session(url).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Failures) > 0 {
+		t.Fatalf("failures = %v", data.Failures)
+	}
+
+	var courses int
+
+	for _, s := range data.Students {
+		for _, c := range s.Courses {
+			courses++
+
+			if len(c.Assignments) != 0 {
+				t.Errorf("course %d has %d assignments, want none", c.ID, len(c.Assignments))
+			}
+		}
+	}
+
+	if courses != 3 {
+		t.Errorf("collected %d courses, want 3", courses)
+	}
+}
+
+func TestSessionPagination(t *testing.T) {
+	demo := Demo()
+	url, requests := serve(t, New(demo))
+
+	data, err := session(url, collect.PageSize(2)).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same(t, data, demo)
+
+	// Five assignments take three pages of two; two take one.
+	want := map[string][]string{"5001": {"1", "2", "3"}, "5002": {"1"}, "6001": {"1"}}
+	if !reflect.DeepEqual(requests.pages, want) {
+		t.Errorf("pages requested = %v, want %v", requests.pages, want)
+	}
+}
+
+func TestSessionLatency(t *testing.T) {
+	const latency = 50 * time.Millisecond
+
+	demo := Demo()
+	url, _ := serve(t, New(demo, Latency(latency)))
+
+	start := time.Now()
+
+	data, err := session(url, collect.Concurrency(1)).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same(t, data, demo)
+
+	// One at a time: the students, the courses of each of the two, and the
+	// details and assignments of each of the three courses.
+	if elapsed := time.Since(start); elapsed < 9*latency {
+		t.Errorf("took %s, want at least %s", elapsed, 9*latency)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), latency/2)
+	defer cancel()
+
+	if _, err := session(url, collect.Retries(0)).DataContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline to be exceeded", err)
+	}
+}
+
+// cookieJar is a collect.CookieStore in memory.
+type cookieJar struct {
+	mu     sync.Mutex
+	sealed []byte
+}
+
+func (j *cookieJar) LoadCookies() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.sealed, nil
+}
+
+func (j *cookieJar) SaveCookies(b []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.sealed = append([]byte(nil), b...)
+
+	return nil
+}
+
+func (j *cookieJar) saved() []byte {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.sealed
+}
+
+func TestSessionReusesCookies(t *testing.T) {
+	demo := Demo()
+	url, requests := serve(t, New(demo))
+
+	key := bytes.Repeat([]byte{1}, collect.CookieKeySize)
+	jar := &cookieJar{}
+
+	for run := 1; run <= 2; run++ {
+		data, err := session(url, collect.Cookies(jar, key)).Data()
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+
+		same(t, data, demo)
+
+		if len(jar.saved()) == 0 {
+			t.Fatalf("run %d saved no cookies", run)
+		}
+	}
+
+	if n := requests.count("/j_security_check"); n != 1 {
+		t.Errorf("logged in %d times, want only on the first run", n)
+	}
+}
+
+func TestSessionReplacesRejectedCookies(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, collect.CookieKeySize)
+
+	tests := map[string]struct {
+		// before runs between the first session and the next.
+		before func(*Portal)
+		// key is what the next session opens the saved cookies with.
+		key []byte
+	}{
+		"expired":   {before: (*Portal).ExpireSessions, key: key},
+		"undecoded": {before: func(*Portal) {}, key: bytes.Repeat([]byte{2}, collect.CookieKeySize)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			demo := Demo()
+			p := New(demo)
+			url, requests := serve(t, p)
+			jar := &cookieJar{}
+
+			if _, err := session(url, collect.Cookies(jar, key)).Data(); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.before(p)
+
+			data, err := session(url, collect.Cookies(jar, tc.key)).Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			same(t, data, demo)
+
+			if n := requests.count("/j_security_check"); n != 2 {
+				t.Errorf("logged in %d times, want once more after the rejected cookies", n)
+			}
+
+			// The new session's cookies were saved in place of the rejected
+			// ones, so the next run needs no login.
+			if _, err := session(url, collect.Cookies(jar, tc.key)).Data(); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := requests.count("/j_security_check"); n != 2 {
+				t.Errorf("logged in %d times, want the saved cookies reused", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package collect
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	columnDrift = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ignitia_collect_column_drift_total",
+		Help: "Assignment rows whose columns differed from those expected, by kind of difference and column.",
+	}, []string{"kind", "column"})
+
+	unknownValues = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ignitia_collect_unknown_values_total",
+		Help: "Assignments whose status or type the portal was not known to use, by field; the values themselves are logged.",
+	}, []string{"field"})
+
+	snapshotDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ignitia_collect_snapshot_duration_seconds",
+		Help:    "Time taken to collect a snapshot, by result.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"result"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ignitia_collect_request_duration_seconds",
+		Help:    "Latency of requests to the portal, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	responses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ignitia_collect_responses_total",
+		Help: `Responses from the portal, by endpoint and HTTP status code; the code is "error" when none arrived.`,
+	}, []string{"endpoint", "code"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ignitia_collect_logins_total",
+		Help: "Attempts to log in to the portal, by result.",
+	}, []string{"result"})
+
+	parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ignitia_collect_parse_errors_total",
+		Help: "Portal responses that could not be parsed, by endpoint.",
+	}, []string{"endpoint"})
+
+	collected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ignitia_collect_collected",
+		Help: "Students, courses and assignments in the last snapshot collected, by kind.",
+	}, []string{"kind"})
+)
+
+// registry holds the collector's metrics, for WriteMetrics. They are kept
+// off the default registry, which serve exposes without ever collecting.
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		columnDrift, unknownValues, snapshotDuration, requestDuration, responses, logins, parseErrors, collected)
+}
+
+// WriteMetrics writes the collector's metrics to path in the text format, for
+// the node exporter's textfile collector to pick up after a snapshot.
+func WriteMetrics(path string) error { return prometheus.WriteToTextfile(path, registry) }
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
@@ -0,0 +1,51 @@
+package collect
+
+import (
+	"sync"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// pool runs tasks on at most size goroutines at a time.
+type pool struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu   sync.Mutex
+	errs error
+}
+
+func newPool(size int) *pool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &pool{slots: make(chan struct{}, size)}
+}
+
+// Go runs task once a slot is free. It blocks until then, so tasks must not
+// themselves call Go on the same pool.
+func (p *pool) Go(task func() error) {
+	p.slots <- struct{}{}
+	p.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-p.slots
+			p.wg.Done()
+		}()
+
+		if err := task(); err != nil {
+			p.mu.Lock()
+			p.errs = multierror.Append(p.errs, err)
+			p.mu.Unlock()
+		}
+	}()
+}
+
+// Wait waits for every task and returns their combined errors.
+func (p *pool) Wait() error {
+	p.wg.Wait()
+
+	return p.errs
+}
@@ -2,16 +2,26 @@ package collect
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"mime"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"golang.org/x/time/rate"
+
 	"github.com/jw4/ignitia.go/pkg/model"
 )
 
@@ -21,233 +31,900 @@ var (
 )
 
 type Session struct {
+	mu         sync.Mutex
 	errSession error
 
+	initMu    sync.Mutex
 	collector *colly.Collector
+	jar       http.CookieJar
 	logger    *log.Logger
 
 	baseURL  string
 	username string
 	password string
+
+	concurrency int
+	limiter     *rate.Limiter
+	retries     int
+	backoff     time.Duration
+	pageSize    int
+	columns     []Column
+	transport   http.RoundTripper
+	calls       *calls
+
+	// drifted holds the column drifts already warned about, and unknowns the
+	// unknown statuses and types.
+	drifted  sync.Map
+	unknowns sync.Map
+
+	cookies      CookieStore
+	cookieKey    []byte
+	cookiesTried bool
+
+	archive Archive
+
+	// collected is when the last snapshot was collected; it is guarded by
+	// mu.
+	collected time.Time
+}
+
+// Option represents a function that can modify a session.
+type Option func(*Session)
+
+// Concurrency limits how many course and assignment requests run at once.
+func Concurrency(n int) Option {
+	return func(s *Session) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// RateLimit limits requests to the portal to rps per second; zero or less
+// removes the limit.
+func RateLimit(rps float64) Option {
+	return func(s *Session) {
+		if rps > 0 {
+			s.limiter = rate.NewLimiter(rate.Limit(rps), 1)
+		} else {
+			s.limiter = rate.NewLimiter(rate.Inf, 1)
+		}
+	}
+}
+
+// Retries sets how many times a failed request is retried.
+func Retries(n int) Option {
+	return func(s *Session) {
+		if n >= 0 {
+			s.retries = n
+		}
+	}
+}
+
+// Backoff sets the delay before the first retry; it doubles, with jitter, for
+// each retry after that.
+func Backoff(d time.Duration) Option {
+	return func(s *Session) {
+		if d > 0 {
+			s.backoff = d
+		}
+	}
 }
 
-func NewSession(baseURL, username, password string) *Session {
+// PageSize sets how many assignments are requested per page.
+func PageSize(n int) Option {
+	return func(s *Session) {
+		if n > 0 {
+			s.pageSize = n
+		}
+	}
+}
+
+// Columns lays out the assignment grid's cells for responses that do not
+// describe their columns, instead of DefaultColumns.
+func Columns(columns ...Column) Option {
+	return func(s *Session) {
+		if len(columns) > 0 {
+			s.columns = columns
+		}
+	}
+}
+
+func NewSession(baseURL, username, password string, opts ...Option) *Session {
 	logger := log.New(os.Stderr, " [session] ", log.LstdFlags)
-	return &Session{
-		baseURL:  baseURL,
-		username: username,
-		password: password,
-		logger:   logger,
+	ses := &Session{
+		baseURL:     baseURL,
+		username:    username,
+		password:    password,
+		logger:      logger,
+		concurrency: defaultConcurrency,
+		limiter:     rate.NewLimiter(defaultRateLimit, 1),
+		retries:     defaultRetries,
+		backoff:     defaultBackoff,
+		pageSize:    defaultPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(ses)
+	}
+
+	ses.calls = &calls{next: ses.transport}
+	if ses.calls.next == nil {
+		ses.calls.next = http.DefaultTransport
 	}
+
+	return ses
+}
+
+const (
+	defaultConcurrency = 4
+	defaultRateLimit   = 4
+	defaultRetries     = 3
+	defaultBackoff     = time.Second
+	defaultPageSize    = 1000
+)
+
+func (s *Session) Error() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.errSession
 }
 
-func (s *Session) Error() error { return s.errSession }
-func (s *Session) Reset()       { s.collector = nil }
+func (s *Session) Reset() {
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
+
+	s.collector = nil
+	s.cookiesTried = false
+	s.setError(nil)
+}
+
+func (s *Session) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errSession = err
+}
 
-func (s *Session) Data() (model.Data, error) {
-	data := model.Data{AsOf: time.Now(), Students: map[int]*model.Student{}}
+// Data collects a full snapshot. Courses for each student, and then details
+// and assignments for each course, are fetched concurrently. Only a failure to
+// list the students fails the snapshot; anything else is recorded in
+// Data.Failures and collection carries on.
+func (s *Session) Data() (model.Data, error) { return s.DataContext(context.Background()) }
+
+// DataContext collects a full snapshot as Data does, abandoning it once ctx
+// is done.
+func (s *Session) DataContext(ctx context.Context) (model.Data, error) {
+	asOf := time.Now()
+
+	s.mu.Lock()
+	s.collected = asOf
+	s.mu.Unlock()
+
+	data, err := gather(withSnapshot(ctx, asOf), s, asOf, s.concurrency)
+	snapshotDuration.WithLabelValues(result(err)).Observe(time.Since(asOf).Seconds())
 
-	students, err := s.Students()
 	if err != nil {
 		return data, err
 	}
 
-	for _, student := range students {
-		student.Courses = map[int]*model.Course{}
+	countCollected(data)
 
-		courses, err := s.Courses(student)
-		if err != nil {
-			return data, err
+	s.initMu.Lock()
+	jar := s.jar
+	s.initMu.Unlock()
+
+	s.saveCookies(jar)
+
+	return data, nil
+}
+
+// countCollected records how much was collected in data.
+func countCollected(data model.Data) {
+	var courses, assignments int
+
+	for _, student := range data.Students {
+		courses += len(student.Courses)
+
+		for _, course := range student.Courses {
+			assignments += len(course.Assignments)
+		}
+	}
+
+	collected.WithLabelValues("students").Set(float64(len(data.Students)))
+	collected.WithLabelValues("courses").Set(float64(courses))
+	collected.WithLabelValues("assignments").Set(float64(assignments))
+}
+
+// source is where a snapshot is gathered from: the portal itself, or the
+// responses archived from it.
+type source interface {
+	students(ctx context.Context) ([]*model.Student, error)
+	courses(ctx context.Context, student *model.Student) ([]*model.Course, error)
+	courseDetails(ctx context.Context, course *model.Course) (*details, error)
+	assignmentPage(
+		ctx context.Context, student *model.Student, course *model.Course, page int,
+	) (*assignmentResponseHelper, error)
+}
+
+// gather assembles the snapshot taken at asOf from src, with up to
+// concurrency requests at once.
+func gather(ctx context.Context, src source, asOf time.Time, concurrency int) (model.Data, error) {
+	data := model.Data{AsOf: asOf, Students: map[int]*model.Student{}}
+
+	students, err := src.students(ctx)
+	if err != nil {
+		return data, err
+	}
+
+	var mu sync.Mutex
+	fail := func(student *model.Student, course *model.Course, stage model.Stage, err error) {
+		failure := model.Failure{
+			StudentID: student.ID,
+			Student:   student.DisplayName,
+			Stage:     stage,
+			Message:   err.Error(),
 		}
 
-		for _, course := range courses {
-			course.Assignments = map[int]*model.Assignment{}
+		if course != nil {
+			failure.CourseID = course.ID
+			failure.Course = course.Title
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
 
-			assignments, err := s.Assignments(student, course)
+		data.Failures = append(data.Failures, failure)
+	}
+
+	work := newPool(concurrency)
+	for _, student := range students {
+		student := student
+		student.Courses = map[int]*model.Course{}
+		data.Students[student.ID] = student
+
+		work.Go(func() error {
+			courses, err := src.courses(ctx, student)
 			if err != nil {
-				return data, err
+				fail(student, nil, model.StageCourses, err)
+				return nil
 			}
 
-			for _, assignment := range assignments {
-				course.Assignments[assignment.ID] = assignment
+			for _, course := range courses {
+				course.Assignments = map[int]*model.Assignment{}
+				student.Courses[course.ID] = course
 			}
 
-			student.Courses[course.ID] = course
+			return nil
+		})
+	}
+
+	if err = wait(ctx, work); err != nil {
+		return data, err
+	}
+
+	// Each course's details are applied once its assignments are in too, so
+	// that only one goroutine at a time touches the course.
+	found := map[*model.Course]*details{}
+
+	work = newPool(concurrency)
+	for _, student := range students {
+		for _, course := range student.Courses {
+			student, course := student, course
+
+			work.Go(func() error {
+				d, err := src.courseDetails(ctx, course)
+				if err != nil {
+					fail(student, course, model.StageDetails, err)
+					return nil
+				}
+
+				mu.Lock()
+				found[course] = d
+				mu.Unlock()
+
+				return nil
+			})
+			work.Go(func() error {
+				assignments, err := assignments(ctx, src, student, course)
+				if err != nil {
+					fail(student, course, model.StageAssignments, err)
+					return nil
+				}
+
+				for _, assignment := range assignments {
+					course.Assignments[assignment.ID] = assignment
+				}
+
+				return nil
+			})
 		}
+	}
 
-		data.Students[student.ID] = student
+	if err = wait(ctx, work); err != nil {
+		return data, err
+	}
+
+	for course, d := range found {
+		d.apply(course)
 	}
 
+	model.SortFailures(data.Failures)
+
 	return data, nil
 }
 
-func (s *Session) Students() ([]*model.Student, error) {
-	if err := s.init(); err != nil {
-		return nil, err
+// wait waits for the work in p, failing once ctx is done since the failures
+// recorded by then are only the abandoned requests.
+func wait(ctx context.Context, p *pool) error {
+	if err := p.Wait(); err != nil {
+		return err
 	}
 
+	return ctx.Err()
+}
+
+func (s *Session) Students() ([]*model.Student, error) { return s.students(context.Background()) }
+
+func (s *Session) students(ctx context.Context) ([]*model.Student, error) {
 	var students []*model.Student
-	loadStudentsFromJSON := func(response *colly.Response) {
-		if !(assertOK(response) && assertJSON(response)) {
-			return
+	loadStudentsFromJSON := func(response *colly.Response) (err error) {
+		if err = expectJSON(response); err != nil {
+			return err
 		}
 
-		if err := json.NewDecoder(bytes.NewReader(response.Body)).Decode(&students); err != nil {
-			s.errSession = err
-			return
-		}
+		s.keep(ctx, studentsName, response.Body)
+		students, err = parseStudents(response.Body)
 
-		sort.Slice(students, func(i, j int) bool { return students[i].ID < students[j].ID })
+		return parsed(response, err)
 	}
 
-	s.getAndUpdate(fmt.Sprintf("%s/owsoo/parent/populateStudents?_=%d", s.baseURL, ts()), loadStudentsFromJSON)
+	err := s.getAndUpdate(ctx, fmt.Sprintf("%s/owsoo/parent/populateStudents?_=%d", s.baseURL, ts()), loadStudentsFromJSON)
 
-	return students, nil
+	return students, err
 }
 
-func (s *Session) Courses(student *model.Student) ([]*model.Course, error) {
-	if err := s.init(); err != nil {
+func parseStudents(body []byte) ([]*model.Student, error) {
+	var students []*model.Student
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&students); err != nil {
 		return nil, err
 	}
 
-	var courses []*model.Course
-	loadCoursesFromJSON := func(response *colly.Response) {
-		if !(assertOK(response) && assertJSON(response)) {
-			return
-		}
+	sort.Slice(students, func(i, j int) bool { return students[i].ID < students[j].ID })
 
-		if err := json.NewDecoder(bytes.NewReader(response.Body)).Decode(&courses); err != nil {
-			s.errSession = err
-			return
-		}
+	return students, nil
+}
 
-		for _, course := range courses {
-			course.StudentID = student.ID
+func (s *Session) Courses(student *model.Student) ([]*model.Course, error) {
+	return s.courses(context.Background(), student)
+}
+
+func (s *Session) courses(ctx context.Context, student *model.Student) ([]*model.Course, error) {
+	var courses []*model.Course
+	loadCoursesFromJSON := func(response *colly.Response) (err error) {
+		if err = expectJSON(response); err != nil {
+			return err
 		}
 
-		sort.Slice(courses, func(i, j int) bool { return courses[i].ID < courses[j].ID })
+		s.keep(ctx, coursesName(student), response.Body)
+		courses, err = parseCourses(response.Body, student)
+
+		return parsed(response, err)
 	}
 
-	s.getAndUpdate(
+	err := s.getAndUpdate(ctx,
 		fmt.Sprintf("%s/owsoo/parent/populateCourses?student_id=%d&_=%d", s.baseURL, student.ID, ts()),
 		loadCoursesFromJSON)
 
-	return courses, s.errSession
+	return courses, err
 }
 
-func (s *Session) Assignments(student *model.Student, course *model.Course) ([]*model.Assignment, error) {
-	if err := s.init(); err != nil {
+func parseCourses(body []byte, student *model.Student) ([]*model.Course, error) {
+	var courses []*model.Course
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&courses); err != nil {
 		return nil, err
 	}
 
-	var helper assignmentResponseHelper
+	for _, course := range courses {
+		course.StudentID = student.ID
+	}
+
+	sort.Slice(courses, func(i, j int) bool { return courses[i].ID < courses[j].ID })
+
+	return courses, nil
+}
+
+func (s *Session) CourseDetails(course *model.Course) error {
+	d, err := s.courseDetails(context.Background(), course)
+	if err != nil {
+		return err
+	}
+
+	d.apply(course)
+
+	return nil
+}
+
+func (s *Session) courseDetails(ctx context.Context, course *model.Course) (*details, error) {
+	var d *details
 
-	loadAssignmentsFromJSON := func(response *colly.Response) {
-		if s.errSession != nil {
-			return
+	loadCourseDetailsFromJSON := func(response *colly.Response) (err error) {
+		if err = expectJSON(response); err != nil {
+			return err
 		}
 
-		if !(assertOK(response) && assertJSON(response)) {
-			return
+		s.keep(ctx, detailsName(course), response.Body)
+		d, err = parseCourseDetails(response.Body)
+
+		return parsed(response, err)
+	}
+
+	err := s.getAndUpdate(ctx,
+		fmt.Sprintf("%s/owsoo/parent/populateCourseDetails?enrollment_id=%d&_=%d", s.baseURL, course.ID, ts()),
+		loadCourseDetailsFromJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// details are the fields of a course taken from its details, leaving the
+// course's id and title as listed.
+type details struct {
+	Subject  string  `json:"courseSubject"`
+	Teacher  string  `json:"teacher"`
+	Start    string  `json:"startDate"`
+	End      string  `json:"endDate"`
+	Progress float64 `json:"currentProgress"`
+	Score    float64 `json:"currentScore"`
+}
+
+func (d *details) apply(course *model.Course) {
+	course.Subject = d.Subject
+	course.Teacher = d.Teacher
+	course.Start = d.Start
+	course.End = d.End
+	course.Progress = d.Progress
+	course.Score = d.Score
+}
+
+func parseCourseDetails(body []byte) (*details, error) {
+	d := &details{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Assignments requests every page of the course's assignments, failing
+// unless the number collected matches the number the portal reports.
+func (s *Session) Assignments(student *model.Student, course *model.Course) ([]*model.Assignment, error) {
+	return assignments(context.Background(), s, student, course)
+}
+
+func assignments(
+	ctx context.Context, src source, student *model.Student, course *model.Course,
+) ([]*model.Assignment, error) {
+	var (
+		assignments []*model.Assignment
+		seen        = map[int]bool{}
+		records     int
+	)
+
+	for page := 1; ; page++ {
+		helper, err := src.assignmentPage(ctx, student, course, page)
+		if err != nil {
+			return nil, err
 		}
 
-		if err := json.NewDecoder(bytes.NewReader(response.Body)).Decode(&helper); err != nil {
-			s.errSession = err
-			return
+		if helper.Page != page && len(helper.Assignments) > 0 {
+			return nil, fmt.Errorf(
+				"requested page %d of assignments for course %d, got page %d [%w]",
+				page, course.ID, helper.Page, ErrValidation)
 		}
 
+		records = helper.Records
+
 		for _, assignment := range helper.Assignments {
-			assignment.StudentID = student.ID
-			assignment.CourseID = course.ID
+			if !seen[assignment.ID] {
+				seen[assignment.ID] = true
+				assignments = append(assignments, assignment)
+			}
+		}
+
+		if len(assignments) >= records || len(helper.Assignments) == 0 || page >= helper.Total {
+			break
+		}
+	}
+
+	if len(assignments) != records {
+		return nil, fmt.Errorf(
+			"collected %d of %d assignments for course %d [%w]",
+			len(assignments), records, course.ID, ErrValidation)
+	}
+
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].DueDate().Before(assignments[j].DueDate())
+	})
+
+	return assignments, nil
+}
+
+func (s *Session) assignmentPage(
+	ctx context.Context, student *model.Student, course *model.Course, page int,
+) (*assignmentResponseHelper, error) {
+	var helper *assignmentResponseHelper
+
+	loadAssignmentsFromJSON := func(response *colly.Response) (err error) {
+		if err = expectJSON(response); err != nil {
+			return err
 		}
+
+		s.keep(ctx, assignmentsName(course, page), response.Body)
+		helper, err = s.parseAssignmentPage(response.Body, student, course)
+
+		return parsed(response, err)
 	}
 
 	data := map[string]string{
 		"student_id":    fmt.Sprintf("%d", student.ID),
 		"enrollment_id": fmt.Sprintf("%d", course.ID),
 		"nd":            fmt.Sprintf("%d", ts()),
-		"rows":          "1000",
-		"page":          "1",
+		"rows":          fmt.Sprintf("%d", s.pageSize),
+		"page":          fmt.Sprintf("%d", page),
 	}
 
-	s.postAndUpdate(
+	err := s.postAndUpdate(ctx,
 		fmt.Sprintf("%s/owsoo/parent/listAssignmentsByCourse", s.baseURL),
 		data, loadAssignmentsFromJSON)
+	if helper == nil {
+		helper = &assignmentResponseHelper{}
+	}
+
+	return helper, err
+}
+
+func (s *Session) parseAssignmentPage(
+	body []byte, student *model.Student, course *model.Course,
+) (*assignmentResponseHelper, error) {
+	helper := &assignmentResponseHelper{columns: s.columns}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(helper); err != nil {
+		return nil, err
+	}
+
+	for _, assignment := range helper.Assignments {
+		assignment.StudentID = student.ID
+		assignment.CourseID = course.ID
+	}
+
+	s.reportDrift(helper.drift)
+	s.reportUnknown(helper.Assignments)
+
+	return helper, nil
+}
 
-	return helper.Assignments, s.errSession
+// reportUnknown counts the assignments with a status or type the portal was
+// not known to use, warning once about each value.
+func (s *Session) reportUnknown(assignments []*model.Assignment) {
+	type unknown struct{ field, value string }
+
+	for _, a := range assignments {
+		var found []unknown
+
+		if !a.Status.Known() {
+			found = append(found, unknown{field: "status", value: string(a.Status)})
+		}
+
+		if !a.Type.Known() {
+			found = append(found, unknown{field: "type", value: string(a.Type)})
+		}
+
+		for _, u := range found {
+			unknownValues.WithLabelValues(u.field).Inc()
+
+			if _, warned := s.unknowns.LoadOrStore(u, true); !warned {
+				s.logger.Printf("warning: unknown assignment %s %q\n", u.field, u.value)
+			}
+		}
+	}
+}
+
+// parsed counts err, if any, as a response from the portal that could not be
+// parsed.
+func parsed(response *colly.Response, err error) error {
+	if err != nil {
+		parseErrors.WithLabelValues(endpoint(scrubURL(response.Request.URL.String()))).Inc()
+	}
+
+	return err
+}
+
+// reportDrift counts the assignment rows that did not match the expected
+// columns, warning once about each difference.
+func (s *Session) reportDrift(found drifts) {
+	for d, rows := range found {
+		columnDrift.WithLabelValues(d.kind, d.column).Add(float64(rows))
+
+		if _, warned := s.drifted.LoadOrStore(d, true); !warned {
+			s.logger.Printf("warning: %s\n", d)
+		}
+	}
 }
 
-func (s *Session) init() error {
-	if s.collector != nil {
-		return nil
+func (s *Session) init(ctx context.Context) error {
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
+
+	// A rejected login is not tried again until Reset, so that concurrent
+	// requests cannot lock the account with repeated attempts. Logging in is
+	// attempted once per call; the request it is made for retries it within
+	// its own budget.
+	if s.collector != nil || s.Error() != nil {
+		return s.Error()
 	}
 
-	s.collector = colly.NewCollector(
+	collector := colly.NewCollector(
 		colly.UserAgent("report-generator v0.0.1 (johnweldon4@gmail.com)"),
+		// Retries repeat the same request.
+		colly.AllowURLRevisit(),
 	)
 
-	s.collector.SetRequestTimeout(30 * time.Second)
+	collector.SetRequestTimeout(30 * time.Second)
+
+	collector.WithTransport(s.calls)
+
+	jar, _ := cookiejar.New(nil)
+	collector.SetCookieJar(jar)
+
+	// Saved cookies are tried once, in place of logging in; if the portal
+	// rejects them the session expires and logs in as usual.
+	if !s.cookiesTried {
+		s.cookiesTried = true
+
+		if s.restoreCookies(jar) {
+			s.collector, s.jar = collector, jar
+			return nil
+		}
+	}
+
+	collector.OnRequest(s.logRequest)
+	collector.OnResponse(s.logResponse)
+
+	collector.OnHTML("#loginForm", func(element *colly.HTMLElement) { s.login(collector, element) })
+	collector.OnHTML(".login-error", s.loginError)
+
+	id, done := s.calls.track(ctx)
+	defer done()
+
+	var status int
+
+	collector.OnError(func(response *colly.Response, _ error) { status = response.StatusCode })
+
+	err := collector.Request(http.MethodGet, s.baseURL, nil, nil, header(collector, id))
+	if err != nil && status >= http.StatusInternalServerError {
+		err = &statusError{code: status, err: err}
+	}
+
+	// Only the caller gave up, so the next request may log in again.
+	if ctx.Err() != nil {
+		s.setError(nil)
+		return ctx.Err()
+	}
+
+	if err == nil {
+		err = s.Error()
+	}
+
+	logins.WithLabelValues(result(err)).Inc()
 
-	s.collector.OnRequest(s.logRequest)
-	s.collector.OnResponse(s.logResponse)
+	if err != nil {
+		// The portal or the network failing is not held against later
+		// requests.
+		if retryable(err) {
+			s.setError(nil)
+		} else {
+			s.setError(err)
+		}
+
+		return err
+	}
 
-	s.collector.OnHTML("#loginForm", s.login)
-	s.collector.OnHTML(".login-error", s.loginError)
+	s.collector, s.jar = collector, jar
+	s.saveCookies(jar)
 
-	if err := s.collector.Visit(s.baseURL); err != nil {
-		s.errSession = err
+	return nil
+}
+
+// relogin replaces the collector whose portal session has expired with a
+// freshly logged in one, unless another request has already done so.
+func (s *Session) relogin(ctx context.Context, expired *colly.Collector) error {
+	s.initMu.Lock()
+	if s.collector == expired {
 		s.collector = nil
 	}
+	s.initMu.Unlock()
 
-	return s.errSession
+	return s.init(ctx)
 }
 
-func (s *Session) login(element *colly.HTMLElement) {
-	data := map[string]string{
-		"j_username": s.username,
-		"j_password": s.password,
+// login posts the credentials as part of the same call as the request that
+// found the login form.
+func (s *Session) login(collector *colly.Collector, element *colly.HTMLElement) {
+	data := url.Values{
+		"j_username": {s.username},
+		"j_password": {s.password},
 	}
 
-	if err := element.Request.Post(element.Attr("action"), data); err != nil {
-		s.errSession = err
+	if err := collector.Request(
+		http.MethodPost,
+		element.Request.AbsoluteURL(element.Attr("action")),
+		strings.NewReader(data.Encode()),
+		element.Request.Ctx,
+		header(collector, element.Request.Headers.Get(callHeader)),
+	); err != nil {
+		s.setError(err)
 	}
 }
 
 func (s *Session) loginError(element *colly.HTMLElement) {
-	s.errSession = fmt.Errorf("error logging in: %s", element.Text)
+	s.setError(fmt.Errorf("error logging in: %s", element.Text))
 }
 
-func (s *Session) getAndUpdate(link string, loader func(*colly.Response)) {
-	if s.errSession != nil {
-		return
+func (s *Session) getAndUpdate(ctx context.Context, link string, loader func(*colly.Response) error) error {
+	return s.request(ctx, loader, func(c *colly.Collector, hdr http.Header) error {
+		return c.Request(http.MethodGet, link, nil, nil, hdr)
+	})
+}
+
+func (s *Session) postAndUpdate(
+	ctx context.Context, link string, data map[string]string, loader func(*colly.Response) error,
+) error {
+	form := url.Values{}
+	for k, v := range data {
+		form.Set(k, v)
+	}
+
+	return s.request(ctx, loader, func(c *colly.Collector, hdr http.Header) error {
+		return c.Request(http.MethodPost, link, strings.NewReader(form.Encode()), nil, hdr)
+	})
+}
+
+// header returns the headers for a request made by collector as part of the
+// call id.
+func header(collector *colly.Collector, id string) http.Header {
+	return http.Header{
+		"User-Agent": {collector.UserAgent},
+		callHeader:   {id},
 	}
+}
 
-	clone := s.collector.Clone()
+// request issues a rate limited request on a clone of the logged in
+// collector, returning either the request error or the loader's error.
+// Transient failures are retried, logging in again first when the portal
+// session has expired.
+func (s *Session) request(
+	ctx context.Context, loader func(*colly.Response) error, do func(*colly.Collector, http.Header) error,
+) error {
+	id, done := s.calls.track(ctx)
+	defer done()
+
+	return s.retry(ctx, func() error {
+		if err := s.init(ctx); err != nil {
+			return err
+		}
 
-	clone.OnRequest(s.logRequest)
-	clone.OnResponse(s.logResponse)
+		if err := s.limiter.Wait(ctx); err != nil {
+			return err
+		}
 
-	clone.OnResponse(loader)
+		s.initMu.Lock()
+		parent := s.collector
+		s.initMu.Unlock()
 
-	if err := clone.Visit(link); err != nil {
-		s.errSession = err
-	}
+		if parent == nil {
+			return ErrSessionExpired
+		}
+
+		clone := parent.Clone()
+
+		clone.OnRequest(s.logRequest)
+		clone.OnResponse(s.logResponse)
+
+		var (
+			status  int
+			errLoad error
+		)
+
+		clone.OnError(func(response *colly.Response, _ error) { status = response.StatusCode })
+		clone.OnResponse(func(response *colly.Response) { errLoad = loader(response) })
+
+		err := do(clone, header(clone, id))
+		if err != nil && status >= http.StatusInternalServerError {
+			err = &statusError{code: status, err: err}
+		}
+
+		if err == nil {
+			err = errLoad
+		}
+
+		if errors.Is(err, ErrSessionExpired) {
+			if errLogin := s.relogin(ctx, parent); errLogin != nil {
+				return errLogin
+			}
+		}
+
+		return err
+	})
 }
 
-func (s *Session) postAndUpdate(link string, data map[string]string, loader func(*colly.Response)) {
-	if s.errSession != nil {
-		return
+// retry runs op until it succeeds, fails permanently, the retry budget is
+// spent or ctx is done, backing off exponentially with jitter between
+// attempts.
+func (s *Session) retry(ctx context.Context, op func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= s.retries || !retryable(err) {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := s.backoff << attempt
+		if delay <= 0 || delay > maxBackoff {
+			delay = maxBackoff
+		}
+
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+		s.logger.Printf("retrying in %s after: %v\n", delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
 	}
+}
+
+const maxBackoff = time.Minute
 
-	clone := s.collector.Clone()
+var ErrSessionExpired = errors.New("portal session expired")
+
+type statusError struct {
+	code int
+	err  error
+}
 
-	clone.OnRequest(s.logRequest)
-	clone.OnResponse(s.logResponse)
+func (e *statusError) Error() string { return fmt.Sprintf("%d: %v", e.code, e.err) }
+func (e *statusError) Unwrap() error { return e.err }
 
-	clone.OnResponse(loader)
+// retryable reports whether err is worth another attempt: an expired session,
+// a server error, or a transport failure such as a timeout.
+func retryable(err error) bool {
+	var (
+		status *statusError
+		urlErr *url.Error
+		netErr net.Error
+	)
 
-	if err := clone.Post(link, data); err != nil {
-		s.errSession = err
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, ErrSessionExpired):
+		return true
+	case errors.As(err, &status):
+		return true
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return true
+	default:
+		return false
 	}
 }
 
@@ -303,6 +980,30 @@ func assertOK(r *colly.Response) bool {
 	return true
 }
 
+// expectJSON checks that response carries JSON, recognizing the login page
+// the portal serves instead once the session has expired.
+func expectJSON(response *colly.Response) error {
+	if !assertOK(response) {
+		return fmt.Errorf("unexpected status %d for %s", response.StatusCode, response.Request.URL)
+	}
+
+	if isLoginPage(response) {
+		return ErrSessionExpired
+	}
+
+	if !assertJSON(response) {
+		return fmt.Errorf("unexpected content type %q for %s", response.Headers.Get("content-type"), response.Request.URL)
+	}
+
+	return nil
+}
+
+func isLoginPage(response *colly.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(response.Headers.Get("content-type"))
+
+	return mediaType == "text/html" && bytes.Contains(response.Body, []byte(`id="loginForm"`))
+}
+
 func assertJSON(response *colly.Response) bool {
 	mediaType, _, err := mime.ParseMediaType(response.Headers.Get("content-type"))
 	if err != nil {
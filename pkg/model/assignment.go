@@ -6,17 +6,20 @@ import (
 )
 
 type Assignment struct {
-	ID        int    `json:"id"`
-	CourseID  int    `json:"course_id"`
-	StudentID int    `json:"student_id"`
-	Unit      int    `json:"unit"`
-	Title     string `json:"title"`
-	Type      string `json:"type"`
-	Progress  int    `json:"progress"`
-	Due       string `json:"due"`
-	Completed string `json:"completed"`
-	Score     int    `json:"score"`
-	Status    string `json:"status"`
+	ID        int            `json:"id"`
+	CourseID  int            `json:"course_id"`
+	StudentID int            `json:"student_id"`
+	Unit      int            `json:"unit"`
+	Title     string         `json:"title"`
+	Type      AssignmentType `json:"type"`
+	Progress  int            `json:"progress"`
+	Due       string         `json:"due"`
+	Completed string         `json:"completed"`
+	Score     int            `json:"score"`
+	Status    Status         `json:"status"`
+
+	// Extra holds the values of grid columns that are not mapped to a field.
+	Extra map[string]interface{} `json:"extra,omitempty"`
 }
 
 func (a *Assignment) String() string {
@@ -26,14 +29,9 @@ func (a *Assignment) String() string {
 func (a *Assignment) CompleteDate() time.Time { return parseDate(a.Completed) }
 func (a *Assignment) DueDate() time.Time      { return parseDate(a.Due) }
 
-func (a *Assignment) IsIncomplete() bool {
-	switch a.Status {
-	case "Skipped", "Completed", "Graded":
-		return false
-	default:
-		return true
-	}
-}
+// IsIncomplete reports whether the assignment still needs work. An unknown
+// status is not taken to mean that it does.
+func (a *Assignment) IsIncomplete() bool { return a.Status.IsOpen() }
 
 func (a *Assignment) IsCurrent() bool {
 	if a.DueDate().After(thisWeek()) && a.DueDate().Before(nextWeek()) {
@@ -47,47 +45,47 @@ func (a *Assignment) IsCurrent() bool {
 	return false
 }
 
-func (a *Assignment) IsFuture() bool { return a.DueDate().After(tomorrow()) }
-func (a *Assignment) IsPast() bool   { return a.DueDate().Before(today()) }
-func (a *Assignment) IsDue() bool {
-	if !a.IsIncomplete() {
+// IsCompletedThisWeek reports whether the assignment was finished during the
+// current week: its status says it needs no more work and it was completed
+// on a day this week. An unknown status does not count as finished.
+func (a *Assignment) IsCompletedThisWeek() bool {
+	if !a.Status.IsTerminal() {
 		return false
 	}
 
-	const finished = 100
-	if a.Progress == finished {
+	completed := a.CompleteDate()
+	if completed.IsZero() {
 		return false
 	}
 
-	if a.DueDate().Before(tomorrow()) {
-		return true
-	}
-
-	return false
+	return !completed.Before(thisWeek()) && completed.Before(nextWeek())
 }
 
-func (a *Assignment) IsOverdue() bool {
-	if !a.IsDue() {
-		return false
-	}
+func (a *Assignment) IsFuture() bool { return a.DueDate().After(tomorrow()) }
+func (a *Assignment) IsPast() bool   { return a.DueDate().Before(today()) }
+func (a *Assignment) IsDue() bool    { return a.IsDueAt(time.Now()) }
 
-	if a.DueDate().Before(ago(7)) {
-		return true
-	}
+// IsDueAt reports whether the assignment was due as of the given time, under
+// the current policy.
+func (a *Assignment) IsDueAt(now time.Time) bool { return CurrentPolicy().IsDueAt(a, now) }
 
-	return false
-}
+func (a *Assignment) IsOverdue() bool { return a.IsOverdueAt(time.Now()) }
 
-func today() time.Time {
-	y, m, d := time.Now().Date()
+// IsOverdueAt reports whether the assignment was overdue as of the given time,
+// under the current policy.
+func (a *Assignment) IsOverdueAt(now time.Time) bool { return CurrentPolicy().IsOverdueAt(a, now) }
+
+func today() time.Time { return DayOf(time.Now()) }
+
+// DayOf returns the start of the local day of t.
+func DayOf(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
 
 	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
 }
 
 func tomorrow() time.Time { return in(1) }
 
-func ago(days int) time.Time { return in(-days) }
-
 func in(days int) time.Time {
 	const day = 24
 
@@ -95,21 +93,15 @@ func in(days int) time.Time {
 }
 
 func thisWeek() time.Time {
-	cur := today()
-
-	offset := int(time.Monday - cur.Weekday())
-	if offset > 0 {
-		const weekBegin = -6
-		offset = weekBegin
-	}
+	start, _ := CurrentPolicy().Week(time.Now())
 
-	return cur.AddDate(0, 0, offset)
+	return start
 }
 
 func nextWeek() time.Time {
-	const daysInWeek = 7
+	_, end := CurrentPolicy().Week(time.Now())
 
-	return thisWeek().AddDate(0, 0, daysInWeek)
+	return end
 }
 
 func parseDate(s string) time.Time {
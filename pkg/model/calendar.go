@@ -0,0 +1,508 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar knows which days are school days: weekdays, other than the
+// holidays and breaks it was loaded with. A nil Calendar counts every day as
+// a school day.
+type Calendar struct {
+	off map[string]bool
+}
+
+// NewCalendar returns a calendar with school on weekdays except the given
+// days.
+func NewCalendar(off ...time.Time) *Calendar {
+	c := &Calendar{off: map[string]bool{}}
+	for _, day := range off {
+		c.off[dateKey(day)] = true
+	}
+
+	return c
+}
+
+// DefaultDaysOff matches the summaries and categories of the iCalendar
+// events that are days without school, unless LoadCalendar is given another
+// pattern.
+var DefaultDaysOff = regexp.MustCompile(`(?i)\b(holidays?|break|vacation|recess|no school|day off|closed)\b`)
+
+// LoadCalendar reads the days without school from the file at path: either
+// an iCalendar file, in which the all-day events whose summary or categories
+// match daysOff are a day or more without school, or a list of dates and
+// date ranges, one per line, such as
+//
+//	# Thanksgiving
+//	2026-11-26
+//	2026-12-21..2027-01-01 Winter break
+//
+// Anything after the date on a line, and lines starting with #, are ignored.
+// A nil daysOff uses DefaultDaysOff.
+func LoadCalendar(path string, daysOff *regexp.Regexp) (*Calendar, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if daysOff == nil {
+		daysOff = DefaultDaysOff
+	}
+
+	c := NewCalendar()
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("BEGIN:VCALENDAR")) {
+		err = c.readICS(bytes.NewReader(b), daysOff)
+	} else {
+		err = c.readDates(bytes.NewReader(b))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// IsSchoolDay reports whether there is school on the day of t.
+func (c *Calendar) IsSchoolDay(t time.Time) bool {
+	if c == nil {
+		return true
+	}
+
+	switch t.In(time.Local).Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+
+	return !c.off[dateKey(t)]
+}
+
+// SchoolDaysBetween counts the school days after the day of from, up to and
+// including the day of to.
+func (c *Calendar) SchoolDaysBetween(from, to time.Time) int {
+	from, to = DayOf(from), DayOf(to)
+
+	if c == nil {
+		const day = 24 * time.Hour
+
+		if !to.After(from) {
+			return 0
+		}
+
+		return int((to.Sub(from) + day/2) / day)
+	}
+
+	return len(c.schoolDays(from, to))
+}
+
+// schoolDaysSince reports whether more than n school days have passed after
+// the day of from, up to and including the day of to, without counting back
+// any further than needed.
+func (c *Calendar) schoolDaysSince(from, to time.Time, n int) bool {
+	if c == nil {
+		return c.SchoolDaysBetween(from, to) > n
+	}
+
+	from = DayOf(from)
+
+	var days int
+	for d := DayOf(to); d.After(from); d = d.AddDate(0, 0, -1) {
+		if c.IsSchoolDay(d) {
+			if days++; days > n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// schoolDays returns the school days after the day of from, up to and
+// including the day of to.
+func (c *Calendar) schoolDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d, last := DayOf(from).AddDate(0, 0, 1), DayOf(to); !d.After(last); d = d.AddDate(0, 0, 1) {
+		if c.IsSchoolDay(d) {
+			days = append(days, d)
+		}
+	}
+
+	return days
+}
+
+const daysInWeek = 7
+
+// searchDays bounds the search for a school day, in case a calendar has none.
+const searchDays = 366
+
+// NextSchoolDay returns the first school day on or after the day of t.
+func (c *Calendar) NextSchoolDay(t time.Time) time.Time {
+	day := DayOf(t)
+	for i := 0; i < searchDays; i++ {
+		if d := day.AddDate(0, 0, i); c.IsSchoolDay(d) {
+			return d
+		}
+	}
+
+	return day
+}
+
+// PreviousSchoolDay returns the last school day on or before the day of t.
+func (c *Calendar) PreviousSchoolDay(t time.Time) time.Time {
+	day := DayOf(t)
+	for i := 0; i < searchDays; i++ {
+		if d := day.AddDate(0, 0, -i); c.IsSchoolDay(d) {
+			return d
+		}
+	}
+
+	return day
+}
+
+func (c *Calendar) addRange(from, through time.Time) {
+	for d := DayOf(from); !d.After(through); d = d.AddDate(0, 0, 1) {
+		c.off[dateKey(d)] = true
+	}
+}
+
+func (c *Calendar) readDates(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		first, last, _ := strings.Cut(fields[0], "..")
+		if last == "" {
+			last = first
+		}
+
+		from, err := time.ParseInLocation("2006-01-02", first, time.Local)
+		if err != nil {
+			return fmt.Errorf("line %d: unrecognized date %q", line, first)
+		}
+
+		through, err := time.ParseInLocation("2006-01-02", last, time.Local)
+		if err != nil {
+			return fmt.Errorf("line %d: unrecognized date %q", line, last)
+		}
+
+		c.addRange(from, through)
+	}
+
+	return scanner.Err()
+}
+
+// icsEvent holds the properties of a VEVENT that decide its days off.
+type icsEvent struct {
+	summary    string
+	categories []string
+	start, end string
+	duration   string
+	rrule      string
+	exdates    []string
+}
+
+// readICS marks the days of the all-day events matching daysOff, and of their
+// recurrences; events with a time of day are ignored.
+func (c *Calendar) readICS(r io.Reader, daysOff *regexp.Regexp) error {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return err
+	}
+
+	var (
+		inEvent bool
+		event   icsEvent
+	)
+
+	for _, line := range lines {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		property, _, _ := strings.Cut(name, ";")
+
+		switch strings.ToUpper(property) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, event = true, icsEvent{}
+			}
+		case "SUMMARY":
+			event.summary = value
+		case "CATEGORIES":
+			event.categories = append(event.categories, strings.Split(value, ",")...)
+		case "DTSTART":
+			event.start = value
+		case "DTEND":
+			event.end = value
+		case "DURATION":
+			event.duration = value
+		case "RRULE":
+			event.rrule = value
+		case "EXDATE":
+			event.exdates = append(event.exdates, strings.Split(value, ",")...)
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+
+			inEvent = false
+
+			if !event.isDayOff(daysOff) {
+				continue
+			}
+
+			if err := c.addEvent(event); err != nil {
+				return fmt.Errorf("%q: %w", event.summary, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isDayOff reports whether the event's summary or one of its categories
+// matches daysOff.
+func (e icsEvent) isDayOff(daysOff *regexp.Regexp) bool {
+	if daysOff.MatchString(e.summary) {
+		return true
+	}
+
+	for _, category := range e.categories {
+		if daysOff.MatchString(category) {
+			return true
+		}
+	}
+
+	return false
+}
+
+const icsDateLayout = "20060102"
+
+func (c *Calendar) addEvent(event icsEvent) error {
+	// Only all-day events, whose start is a bare date, take a day off.
+	if len(event.start) != len(icsDateLayout) {
+		return nil
+	}
+
+	from, err := time.ParseInLocation(icsDateLayout, event.start, time.Local)
+	if err != nil {
+		return fmt.Errorf("unrecognized DTSTART %q", event.start)
+	}
+
+	// The end of an all-day event is the day after its last day.
+	days := 1
+	switch {
+	case event.end != "":
+		until, err := time.ParseInLocation(icsDateLayout, event.end, time.Local)
+		if err != nil {
+			return fmt.Errorf("unrecognized DTEND %q", event.end)
+		}
+
+		days = int(DayOf(until).Sub(from).Hours()/24 + 0.5)
+	case event.duration != "":
+		if days, err = icsDays(event.duration); err != nil {
+			return err
+		}
+	}
+
+	occurrences := []time.Time{from}
+	if event.rrule != "" {
+		if occurrences, err = recur(from, event.rrule); err != nil {
+			return err
+		}
+	}
+
+	excluded := map[string]bool{}
+	for _, exdate := range event.exdates {
+		if len(exdate) < len(icsDateLayout) {
+			return fmt.Errorf("unrecognized EXDATE %q", exdate)
+		}
+
+		d, err := time.ParseInLocation(icsDateLayout, exdate[:len(icsDateLayout)], time.Local)
+		if err != nil {
+			return fmt.Errorf("unrecognized EXDATE %q", exdate)
+		}
+
+		excluded[dateKey(d)] = true
+	}
+
+	for _, d := range occurrences {
+		if !excluded[dateKey(d)] {
+			c.addRange(d, d.AddDate(0, 0, days-1))
+		}
+	}
+
+	return nil
+}
+
+// openRecurrence bounds a recurrence rule with neither COUNT nor UNTIL.
+const openRecurrence = 50
+
+// recur expands the recurrence rule of an all-day event starting on from into
+// the days it occurs on, from included. Only DAILY, WEEKLY and YEARLY rules,
+// with INTERVAL, COUNT, UNTIL and, for WEEKLY rules, BYDAY are supported.
+func recur(from time.Time, rule string) ([]time.Time, error) {
+	var (
+		years, days int
+		interval    = 1
+		count       int
+		until       = from.AddDate(openRecurrence, 0, 0)
+		byDay       []time.Weekday
+	)
+
+	for _, part := range strings.Split(rule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+
+		var err error
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			switch strings.ToUpper(value) {
+			case "DAILY":
+				days = 1
+			case "WEEKLY":
+				days = daysInWeek
+			case "YEARLY":
+				years = 1
+			default:
+				return nil, fmt.Errorf("unsupported RRULE %q", rule)
+			}
+		case "INTERVAL":
+			if interval, err = strconv.Atoi(value); err != nil || interval < 1 {
+				return nil, fmt.Errorf("unsupported RRULE %q", rule)
+			}
+		case "COUNT":
+			if count, err = strconv.Atoi(value); err != nil || count < 1 {
+				return nil, fmt.Errorf("unsupported RRULE %q", rule)
+			}
+		case "UNTIL":
+			if len(value) < len(icsDateLayout) {
+				return nil, fmt.Errorf("unsupported RRULE %q", rule)
+			}
+
+			if until, err = time.ParseInLocation(icsDateLayout, value[:len(icsDateLayout)], time.Local); err != nil {
+				return nil, fmt.Errorf("unsupported RRULE %q", rule)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := icsWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE %q", rule)
+				}
+
+				byDay = append(byDay, weekday)
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported RRULE %q", rule)
+		}
+	}
+
+	if years == 0 && days == 0 || len(byDay) > 0 && days != daysInWeek {
+		return nil, fmt.Errorf("unsupported RRULE %q", rule)
+	}
+
+	var occurrences []time.Time
+
+	for i := 0; ; i++ {
+		period := from.AddDate(years*interval*i, 0, days*interval*i)
+		if period.After(until) && (len(byDay) == 0 || period.AddDate(0, 0, -daysInWeek).After(until)) {
+			return occurrences, nil
+		}
+
+		candidates := []time.Time{period}
+		if len(byDay) > 0 {
+			candidates = weekDays(period, byDay)
+		}
+
+		for _, d := range candidates {
+			if d.Before(from) || d.After(until) {
+				continue
+			}
+
+			occurrences = append(occurrences, d)
+			if count > 0 && len(occurrences) == count {
+				return occurrences, nil
+			}
+		}
+	}
+}
+
+// weekDays returns the given days of the week, starting on Monday, that d
+// falls in, in order.
+func weekDays(d time.Time, weekdays []time.Weekday) []time.Time {
+	monday := d.AddDate(0, 0, -(int(d.Weekday())+daysInWeek-1)%daysInWeek)
+
+	var found []time.Time
+	for i := 0; i < daysInWeek; i++ {
+		day := monday.AddDate(0, 0, i)
+		for _, weekday := range weekdays {
+			if day.Weekday() == weekday {
+				found = append(found, day)
+			}
+		}
+	}
+
+	return found
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// icsDays parses a whole number of days or weeks from an iCalendar duration,
+// such as P3D or P1W.
+func icsDays(duration string) (int, error) {
+	s := strings.TrimPrefix(strings.ToUpper(duration), "P")
+
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "W"):
+		unit = daysInWeek
+	case !strings.HasSuffix(s, "D"):
+		return 0, fmt.Errorf("unsupported DURATION %q", duration)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("unsupported DURATION %q", duration)
+	}
+
+	return n * unit, nil
+}
+
+// unfoldICS joins the continuation lines of an iCalendar file, which start
+// with a space or tab, to the lines they continue.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func dateKey(t time.Time) string { return t.In(time.Local).Format("2006-01-02") }
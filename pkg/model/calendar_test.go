@@ -0,0 +1,208 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCalendarSchoolDays(t *testing.T) {
+	// Labor Day, and the Thanksgiving break.
+	c := NewCalendar(day("2022-09-05"))
+	c.addRange(day("2022-11-23"), day("2022-11-25"))
+
+	tests := map[string]struct {
+		c        *Calendar
+		from, to string
+		want     int
+	}{
+		"every day":           {nil, "2022-09-01", "2022-09-08", 7},
+		"every day, reversed": {nil, "2022-09-08", "2022-09-01", 0},
+		"every day, same day": {nil, "2022-09-01 08:00", "2022-09-01 20:00", 0},
+		"weekend and holiday": {c, "2022-09-01", "2022-09-08", 4},
+		"break":               {c, "2022-11-18", "2022-11-28", 3},
+		"from a weekend":      {c, "2022-09-03", "2022-09-06", 1},
+	}
+
+	for name, tc := range tests {
+		if got := tc.c.SchoolDaysBetween(day(tc.from), day(tc.to)); got != tc.want {
+			t.Errorf("%s: SchoolDaysBetween = %d, want %d", name, got, tc.want)
+		}
+	}
+
+	days := map[string]struct {
+		schoolDay      bool
+		next, previous string
+	}{
+		"2022-09-02": {true, "2022-09-02", "2022-09-02"},
+		"2022-09-03": {false, "2022-09-06", "2022-09-02"},
+		"2022-09-05": {false, "2022-09-06", "2022-09-02"},
+		"2022-11-24": {false, "2022-11-28", "2022-11-22"},
+	}
+
+	for d, want := range days {
+		if got := c.IsSchoolDay(day(d)); got != want.schoolDay {
+			t.Errorf("%s: IsSchoolDay = %t, want %t", d, got, want.schoolDay)
+		}
+
+		if got := c.NextSchoolDay(day(d + " 13:00")); !got.Equal(day(want.next)) {
+			t.Errorf("%s: NextSchoolDay = %s, want %s", d, got, want.next)
+		}
+
+		if got := c.PreviousSchoolDay(day(d)); !got.Equal(day(want.previous)) {
+			t.Errorf("%s: PreviousSchoolDay = %s, want %s", d, got, want.previous)
+		}
+	}
+
+	var none *Calendar
+	if !none.IsSchoolDay(day("2022-09-03")) {
+		t.Error("a nil calendar has school every day")
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	tests := map[string]struct {
+		file    string
+		daysOff *regexp.Regexp
+		off     []string
+		err     string
+	}{
+		"dates": {
+			file: "# Labor Day\n2022-09-05\n\n2022-11-23..2022-11-25 Thanksgiving break\n",
+			off:  []string{"2022-09-05", "2022-11-23", "2022-11-24", "2022-11-25"},
+		},
+		"bad date": {
+			file: "2022-09-05\nsoon\n",
+			err:  `line 2: unrecognized date "soon"`,
+		},
+		"bad range": {
+			file: "2022-11-23..later\n",
+			err:  `line 1: unrecognized date "later"`,
+		},
+		"ics": {
+			file: strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"SUMMARY:Labor",
+				"  Day",
+				"CATEGORIES:School,Holiday",
+				"DTSTART;VALUE=DATE:20220905",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Early release",
+				"DTSTART;VALUE=DATE:20221010",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Picture day",
+				"DTSTART;VALUE=DATE:20221122",
+				"DTEND;VALUE=DATE:20221123",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Thanksgiving break",
+				"DTSTART;VALUE=DATE:20221123",
+				"DTEND;VALUE=DATE:20221126",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Winter break",
+				"DTSTART;VALUE=DATE:20221219",
+				"DURATION:P2W",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Conferences",
+				"DTSTART:20221010T150000Z",
+				"DTEND:20221010T180000Z",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			}, "\r\n"),
+			off: []string{"2022-09-05", "2022-11-23", "2022-11-25", "2022-12-19", "2022-12-30"},
+		},
+		"ics pattern": {
+			file: strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"SUMMARY:Labor Day",
+				"DTSTART;VALUE=DATE:20220905",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Thanksgiving break",
+				"DTSTART;VALUE=DATE:20221123",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			}, "\n"),
+			daysOff: regexp.MustCompile(`Day$`),
+			off:     []string{"2022-09-05"},
+		},
+		"ics recurring": {
+			file: strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"SUMMARY:Fall break",
+				"DTSTART;VALUE=DATE:20211123",
+				"DTEND;VALUE=DATE:20211126",
+				"RRULE:FREQ=YEARLY;COUNT=2",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:No school",
+				"DTSTART;VALUE=DATE:20220905",
+				"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20221031T000000Z",
+				"EXDATE;VALUE=DATE:20221003,20221007",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"SUMMARY:Early release",
+				"DTSTART;VALUE=DATE:20220907",
+				"RRULE:FREQ=MONTHLY;BYDAY=1WE",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			}, "\n"),
+			off: []string{
+				"2021-11-23", "2022-11-23", "2022-11-25",
+				"2022-09-05", "2022-09-09", "2022-09-19", "2022-09-23", "2022-10-17", "2022-10-21",
+			},
+		},
+		"ics unsupported rule": {
+			file: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Holiday\nDTSTART;VALUE=DATE:20221219\n" +
+				"RRULE:FREQ=MONTHLY;BYMONTHDAY=19\nEND:VEVENT\nEND:VCALENDAR\n",
+			err: `"Holiday": unsupported RRULE "FREQ=MONTHLY;BYMONTHDAY=19"`,
+		},
+		"bad duration": {
+			file: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Winter break\nDTSTART;VALUE=DATE:20221219\n" +
+				"DURATION:PT4H\nEND:VEVENT\nEND:VCALENDAR\n",
+			err: `unsupported DURATION "PT4H"`,
+		},
+	}
+
+	for name, tc := range tests {
+		path := filepath.Join(t.TempDir(), "calendar")
+		if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := LoadCalendar(path, tc.daysOff)
+
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: err = %v, want %q", name, err, tc.err)
+			}
+
+			continue
+		case err != nil:
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		for _, d := range tc.off {
+			if c.IsSchoolDay(day(d)) {
+				t.Errorf("%s: %s is a school day", name, d)
+			}
+		}
+
+		for _, d := range []string{"2022-09-06", "2022-10-10", "2022-11-22", "2022-11-28", "2023-01-02"} {
+			if !c.IsSchoolDay(day(d)) {
+				t.Errorf("%s: %s is not a school day", name, d)
+			}
+		}
+	}
+}
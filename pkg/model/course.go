@@ -1,14 +1,30 @@
 package model
 
-import "sort"
+import (
+	"sort"
+	"time"
+)
 
 type Course struct {
 	ID          int                 `json:"id"`
 	StudentID   int                 `json:"student_id"`
 	Title       string              `json:"title"`
+	Subject     string              `json:"subject,omitempty"`
+	Teacher     string              `json:"teacher,omitempty"`
+	Start       string              `json:"start_date,omitempty"`
+	End         string              `json:"end_date,omitempty"`
+	Progress    float64             `json:"progress,omitempty"`
+	Score       float64             `json:"score,omitempty"`
+	Stale       *time.Time          `json:"stale,omitempty"`
 	Assignments map[int]*Assignment `json:"assignments"`
+
+	// whole is the unfiltered course, when this is a filtered copy of it.
+	whole *Course
 }
 
+func (c *Course) StartDate() time.Time { return parseDate(c.Start) }
+func (c *Course) EndDate() time.Time   { return parseDate(c.End) }
+
 func (c *Course) SortedAssignments() []*Assignment {
 	var assignments []*Assignment
 	for _, assignment := range c.Assignments {
@@ -70,3 +86,15 @@ func (c *Course) OverdueAssignments() int {
 
 	return overdue
 }
+
+func (c *Course) CompletedThisWeek() int {
+	var completed int
+
+	for _, a := range c.Assignments {
+		if a.IsCompletedThisWeek() {
+			completed++
+		}
+	}
+
+	return completed
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type ChangeKind string
+
+const (
+	CourseAdded       ChangeKind = "course-added"
+	CourseDropped     ChangeKind = "course-dropped"
+	AssignmentAdded   ChangeKind = "assignment-added"
+	AssignmentRemoved ChangeKind = "assignment-removed"
+	StatusChanged     ChangeKind = "status-changed"
+	ScoreChanged      ChangeKind = "score-changed"
+	ProgressChanged   ChangeKind = "progress-changed"
+	DueChanged        ChangeKind = "due-changed"
+)
+
+// Change is a single difference between two snapshots. Assignment fields are
+// empty for course level changes, and From and To are empty for additions and
+// removals.
+type Change struct {
+	Kind         ChangeKind `json:"kind"`
+	StudentID    int        `json:"student_id"`
+	Student      string     `json:"student"`
+	CourseID     int        `json:"course_id"`
+	Course       string     `json:"course"`
+	AssignmentID int        `json:"assignment_id,omitempty"`
+	Assignment   string     `json:"assignment,omitempty"`
+	From         string     `json:"from,omitempty"`
+	To           string     `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	where := fmt.Sprintf("%s / %s", html.UnescapeString(c.Student), html.UnescapeString(c.Course))
+	if c.Assignment != "" {
+		where = fmt.Sprintf("%s / %q", where, c.Assignment)
+	}
+
+	switch c.Kind {
+	case CourseAdded, CourseDropped, AssignmentAdded, AssignmentRemoved:
+		return fmt.Sprintf("%s: %s", where, c.Kind)
+	default:
+		return fmt.Sprintf("%s: %s %q -> %q", where, c.Kind, c.From, c.To)
+	}
+}
+
+type Changes struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Changes []Change  `json:"changes"`
+}
+
+// Diff reports what changed between two snapshots, ordered by student, course
+// and assignment.
+func Diff(old, new Data) Changes {
+	changes := Changes{From: old.AsOf, To: new.AsOf}
+
+	for _, student := range unionStudents(old, new) {
+		before, after := old.Students[student.ID], new.Students[student.ID]
+
+		for _, course := range unionCourses(before, after) {
+			changes.Changes = append(changes.Changes, diffCourse(student, course, before, after)...)
+		}
+	}
+
+	return changes
+}
+
+func diffCourse(student, course namedID, before, after *Student) []Change {
+	var (
+		changes []Change
+		lhs     = courseOf(before, course.ID)
+		rhs     = courseOf(after, course.ID)
+		change  = func(kind ChangeKind, a *Assignment, from, to string) {
+			c := Change{
+				Kind:      kind,
+				StudentID: student.ID,
+				Student:   student.Name,
+				CourseID:  course.ID,
+				Course:    course.Name,
+				From:      from,
+				To:        to,
+			}
+
+			if a != nil {
+				c.AssignmentID = a.ID
+				c.Assignment = a.Title
+			}
+
+			changes = append(changes, c)
+		}
+	)
+
+	switch {
+	case lhs == nil:
+		change(CourseAdded, nil, "", "")
+	case rhs == nil:
+		change(CourseDropped, nil, "", "")
+
+		return changes
+	}
+
+	var previous map[int]*Assignment
+	if lhs != nil {
+		previous = lhs.Assignments
+	}
+
+	for _, a := range rhs.SortedAssignments() {
+		p, ok := previous[a.ID]
+		if !ok {
+			change(AssignmentAdded, a, "", "")
+			continue
+		}
+
+		if p.Status != a.Status {
+			change(StatusChanged, a, string(p.Status), string(a.Status))
+		}
+
+		if p.Score != a.Score {
+			change(ScoreChanged, a, strconv.Itoa(p.Score), strconv.Itoa(a.Score))
+		}
+
+		if p.Progress != a.Progress {
+			change(ProgressChanged, a, strconv.Itoa(p.Progress), strconv.Itoa(a.Progress))
+		}
+
+		if p.Due != a.Due {
+			change(DueChanged, a, p.Due, a.Due)
+		}
+	}
+
+	if lhs != nil {
+		for _, a := range lhs.SortedAssignments() {
+			if _, ok := rhs.Assignments[a.ID]; !ok {
+				change(AssignmentRemoved, a, "", "")
+			}
+		}
+	}
+
+	return changes
+}
+
+type namedID struct {
+	ID   int
+	Name string
+}
+
+func unionStudents(old, new Data) []namedID {
+	var ids []namedID
+
+	seen := map[int]bool{}
+	for _, data := range []Data{new, old} {
+		for _, student := range data.SortedStudents() {
+			if !seen[student.ID] {
+				seen[student.ID] = true
+				ids = append(ids, namedID{ID: student.ID, Name: student.DisplayName})
+			}
+		}
+	}
+
+	sortNamed(ids)
+
+	return ids
+}
+
+func unionCourses(before, after *Student) []namedID {
+	var ids []namedID
+
+	seen := map[int]bool{}
+	for _, student := range []*Student{after, before} {
+		if student == nil {
+			continue
+		}
+
+		for _, course := range student.SortedCourses() {
+			if !seen[course.ID] {
+				seen[course.ID] = true
+				ids = append(ids, namedID{ID: course.ID, Name: course.Title})
+			}
+		}
+	}
+
+	sortNamed(ids)
+
+	return ids
+}
+
+func sortNamed(ids []namedID) {
+	sort.SliceStable(ids, func(x, y int) bool { return ids[x].Name < ids[y].Name })
+}
+
+func courseOf(student *Student, id int) *Course {
+	if student == nil {
+		return nil
+	}
+
+	return student.Courses[id]
+}
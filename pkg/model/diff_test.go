@@ -0,0 +1,180 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// diffSnapshots returns two snapshots of one student two weeks apart, between
+// which every kind of change happens.
+func diffSnapshots() (Data, Data) {
+	course := func(id int, title string, assignments ...*Assignment) *Course {
+		c := &Course{ID: id, StudentID: 1, Title: title, Assignments: map[int]*Assignment{}}
+		for _, a := range assignments {
+			a.StudentID, a.CourseID, a.Unit = 1, id, 1
+			c.Assignments[a.ID] = a
+		}
+
+		return c
+	}
+
+	snapshot := func(asOf time.Time, courses ...*Course) Data {
+		s := &Student{ID: 1, DisplayName: "Anna", Courses: map[int]*Course{}}
+		for _, c := range courses {
+			s.Courses[c.ID] = c
+		}
+
+		return Data{AsOf: asOf, Students: map[int]*Student{1: s}}
+	}
+
+	old := snapshot(time.Date(2022, 9, 1, 8, 0, 0, 0, time.Local),
+		course(8, "Art"),
+		course(7, "Math",
+			&Assignment{ID: 1, Title: "Fractions", Type: TypeLesson, Due: "2022-09-05", Status: StatusNotStarted},
+			&Assignment{ID: 2, Title: "Decimals", Type: TypeLesson, Progress: 50, Due: "2022-08-01", Status: StatusInProgress},
+			&Assignment{ID: 3, Title: "Ratios", Type: TypeQuiz, Progress: 100, Due: "2022-08-30", Status: StatusCompleted},
+			&Assignment{ID: 4, Title: "Dropped", Type: TypeLesson, Due: "2022-09-02", Status: StatusNotStarted},
+			&Assignment{ID: 6, Title: "Graphs", Type: TypeLesson, Due: "2022-09-20", Status: StatusNotStarted},
+		))
+
+	new := snapshot(time.Date(2022, 9, 15, 8, 0, 0, 0, time.Local),
+		course(7, "Math",
+			&Assignment{ID: 1, Title: "Fractions", Type: TypeLesson, Progress: 100, Due: "2022-09-05", Status: StatusCompleted},
+			&Assignment{ID: 2, Title: "Decimals", Type: TypeLesson, Progress: 50, Due: "2022-08-01", Status: StatusInProgress},
+			&Assignment{ID: 3, Title: "Ratios", Type: TypeQuiz, Progress: 100, Due: "2022-08-30", Score: 90, Status: StatusGraded},
+			&Assignment{ID: 5, Title: "Percents", Type: TypeLesson, Due: "2022-09-01", Status: StatusNotStarted},
+			&Assignment{ID: 6, Title: "Graphs", Type: TypeLesson, Due: "2022-09-22", Status: StatusNotStarted},
+		),
+		course(9, "Science",
+			&Assignment{ID: 7, Title: "Cells", Type: TypeLesson, Due: "2022-10-01", Status: StatusNotStarted},
+		))
+
+	return old, new
+}
+
+func TestDiff(t *testing.T) {
+	old, new := diffSnapshots()
+
+	change := func(kind ChangeKind, courseID int, course string, id int, title, from, to string) Change {
+		return Change{
+			Kind: kind, StudentID: 1, Student: "Anna", CourseID: courseID, Course: course,
+			AssignmentID: id, Assignment: title, From: from, To: to,
+		}
+	}
+
+	tests := map[string]struct {
+		old, new Data
+		want     []Change
+	}{
+		"unchanged": {old, old, nil},
+		"changed": {old, new, []Change{
+			change(CourseDropped, 8, "Art", 0, "", "", ""),
+			change(StatusChanged, 7, "Math", 3, "Ratios", "Completed", "Graded"),
+			change(ScoreChanged, 7, "Math", 3, "Ratios", "0", "90"),
+			change(AssignmentAdded, 7, "Math", 5, "Percents", "", ""),
+			change(StatusChanged, 7, "Math", 1, "Fractions", "Not Started", "Completed"),
+			change(ProgressChanged, 7, "Math", 1, "Fractions", "0", "100"),
+			change(DueChanged, 7, "Math", 6, "Graphs", "2022-09-20", "2022-09-22"),
+			change(AssignmentRemoved, 7, "Math", 4, "Dropped", "", ""),
+			change(CourseAdded, 9, "Science", 0, "", "", ""),
+			change(AssignmentAdded, 9, "Science", 7, "Cells", "", ""),
+		}},
+		"from nothing": {Data{}, Data{Students: map[int]*Student{1: old.Students[1]}}, []Change{
+			change(CourseAdded, 8, "Art", 0, "", "", ""),
+			change(CourseAdded, 7, "Math", 0, "", "", ""),
+			change(AssignmentAdded, 7, "Math", 2, "Decimals", "", ""),
+			change(AssignmentAdded, 7, "Math", 3, "Ratios", "", ""),
+			change(AssignmentAdded, 7, "Math", 4, "Dropped", "", ""),
+			change(AssignmentAdded, 7, "Math", 1, "Fractions", "", ""),
+			change(AssignmentAdded, 7, "Math", 6, "Graphs", "", ""),
+		}},
+	}
+
+	for name, tc := range tests {
+		got := Diff(tc.old, tc.new)
+
+		if !got.From.Equal(tc.old.AsOf) || !got.To.Equal(tc.new.AsOf) {
+			t.Errorf("%s: from %s to %s, want %s to %s", name, got.From, got.To, tc.old.AsOf, tc.new.AsOf)
+		}
+
+		if !reflect.DeepEqual(got.Changes, tc.want) {
+			t.Errorf("%s: changes\n%v\nwant\n%v", name, got.Changes, tc.want)
+		}
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	tests := map[string]struct {
+		change Change
+		want   string
+	}{
+		"course": {
+			Change{Kind: CourseAdded, Student: "Anna &amp; Co", Course: "Reading &amp; Writing"},
+			"Anna & Co / Reading & Writing: course-added",
+		},
+		"assignment": {
+			Change{Kind: AssignmentRemoved, Student: "Anna", Course: "Math", Assignment: "Ratios"},
+			`Anna / Math / "Ratios": assignment-removed`,
+		},
+		"field": {
+			Change{Kind: ScoreChanged, Student: "Anna", Course: "Math", Assignment: "Ratios", From: "0", To: "90"},
+			`Anna / Math / "Ratios": score-changed "0" -> "90"`,
+		},
+	}
+
+	for name, tc := range tests {
+		if got := tc.change.String(); got != tc.want {
+			t.Errorf("%s: %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	old, new := diffSnapshots()
+
+	type event struct {
+		kind       EventKind
+		assignment int
+		previous   bool
+	}
+
+	want := []event{
+		{EventGraded, 3, true},
+		{EventScoreChanged, 3, true},
+		{EventNew, 5, false},
+		{EventCompleted, 1, true},
+		{EventNew, 7, false},
+		// Decimals was already overdue, Percents is newly so.
+		{EventBecameOverdue, 5, false},
+	}
+
+	var got []event
+	for _, e := range Events(old, new) {
+		got = append(got, event{e.Kind, e.Assignment.ID, e.Previous != nil})
+
+		if !e.AsOf.Equal(new.AsOf) || e.StudentID != 1 || e.Student != "Anna" {
+			t.Errorf("%s %d: as of %s for %d %q", e.Kind, e.Assignment.ID, e.AsOf, e.StudentID, e.Student)
+		}
+
+		if e.Course != new.Students[1].Courses[e.CourseID].Title {
+			t.Errorf("%s %d: course %d %q", e.Kind, e.Assignment.ID, e.CourseID, e.Course)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events\n%v\nwant\n%v", got, want)
+	}
+
+	if events := Events(new, new); len(events) != 0 {
+		t.Errorf("events between equal snapshots: %v", events)
+	}
+}
+
+func TestEventSubject(t *testing.T) {
+	e := Event{Kind: EventBecameOverdue, StudentID: 1, CourseID: 7, Assignment: &Assignment{ID: 3}}
+
+	if got, want := e.Subject("ignitia"), "ignitia.1.7.assignment.3.became-overdue"; got != want {
+		t.Errorf("subject %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventNew           EventKind = "new"
+	EventCompleted     EventKind = "completed"
+	EventGraded        EventKind = "graded"
+	EventScoreChanged  EventKind = "score-changed"
+	EventBecameOverdue EventKind = "became-overdue"
+)
+
+// Event is a change to a single assignment worth notifying about.
+type Event struct {
+	Kind       EventKind   `json:"kind"`
+	StudentID  int         `json:"student_id"`
+	Student    string      `json:"student"`
+	CourseID   int         `json:"course_id"`
+	Course     string      `json:"course"`
+	Assignment *Assignment `json:"assignment"`
+	Previous   *Assignment `json:"previous,omitempty"`
+	AsOf       time.Time   `json:"as_of"`
+}
+
+// Subject is the dotted subject the event is published on, below prefix.
+func (e Event) Subject(prefix string) string {
+	return fmt.Sprintf("%s.%d.%d.assignment.%d.%s", prefix, e.StudentID, e.CourseID, e.Assignment.ID, e.Kind)
+}
+
+// Events derives the assignment events between two consecutive snapshots.
+func Events(old, new Data) []Event {
+	var events []Event
+
+	event := func(kind EventKind, student *Student, course *Course, a *Assignment) Event {
+		e := Event{
+			Kind:       kind,
+			StudentID:  student.ID,
+			Student:    student.DisplayName,
+			CourseID:   course.ID,
+			Course:     course.Title,
+			Assignment: a,
+			AsOf:       new.AsOf,
+		}
+
+		if prev := courseOf(old.Students[student.ID], course.ID); prev != nil {
+			e.Previous = prev.Assignments[a.ID]
+		}
+
+		return e
+	}
+
+	for _, change := range Diff(old, new).Changes {
+		student := new.Students[change.StudentID]
+		course := courseOf(student, change.CourseID)
+		if course == nil {
+			continue
+		}
+
+		a := course.Assignments[change.AssignmentID]
+		if a == nil {
+			continue
+		}
+
+		switch change.Kind {
+		case AssignmentAdded:
+			events = append(events, event(EventNew, student, course, a))
+		case StatusChanged:
+			switch a.Status {
+			case StatusCompleted:
+				events = append(events, event(EventCompleted, student, course, a))
+			case StatusGraded:
+				events = append(events, event(EventGraded, student, course, a))
+			}
+		case ScoreChanged:
+			events = append(events, event(EventScoreChanged, student, course, a))
+		}
+	}
+
+	for _, student := range new.SortedStudents() {
+		for _, course := range student.SortedCourses() {
+			for _, a := range course.SortedAssignments() {
+				if !a.IsOverdueAt(new.AsOf) {
+					continue
+				}
+
+				prev := courseOf(old.Students[student.ID], course.ID)
+				if prev != nil && prev.Assignments[a.ID] != nil && prev.Assignments[a.ID].IsOverdueAt(old.AsOf) {
+					continue
+				}
+
+				events = append(events, event(EventBecameOverdue, student, course, a))
+			}
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"time"
+)
+
+type Stage string
+
+const (
+	StageCourses     Stage = "courses"
+	StageDetails     Stage = "details"
+	StageAssignments Stage = "assignments"
+)
+
+// Failure records part of a snapshot that could not be collected. CourseID is
+// zero when the course list itself failed.
+type Failure struct {
+	StudentID int    `json:"student_id"`
+	Student   string `json:"student"`
+	CourseID  int    `json:"course_id,omitempty"`
+	Course    string `json:"course,omitempty"`
+	Stage     Stage  `json:"stage"`
+	Message   string `json:"message"`
+}
+
+func (f Failure) Error() string {
+	where := html.UnescapeString(f.Student)
+	if f.Course != "" {
+		where = fmt.Sprintf("%s / %s", where, html.UnescapeString(f.Course))
+	}
+
+	return fmt.Sprintf("%s: collecting %s: %s", where, f.Stage, f.Message)
+}
+
+// SortFailures orders failures by student, course and stage.
+func SortFailures(failures []Failure) {
+	sort.Slice(failures, func(x, y int) bool {
+		lhs, rhs := failures[x], failures[y]
+		if lhs.StudentID != rhs.StudentID {
+			return lhs.StudentID < rhs.StudentID
+		}
+
+		if lhs.CourseID != rhs.CourseID {
+			return lhs.CourseID < rhs.CourseID
+		}
+
+		return lhs.Stage < rhs.Stage
+	})
+}
+
+// Carry fills in the parts of d that failed to collect with their last known
+// values from previous, marking the affected courses stale.
+func (d *Data) Carry(previous Data) {
+	for _, f := range d.Failures {
+		student, prevStudent := d.Students[f.StudentID], previous.Students[f.StudentID]
+		if student == nil || prevStudent == nil {
+			continue
+		}
+
+		if f.Stage == StageCourses {
+			if student.Courses == nil {
+				student.Courses = map[int]*Course{}
+			}
+
+			for id, prev := range prevStudent.Courses {
+				if _, ok := student.Courses[id]; !ok {
+					course := *prev
+					course.markStale(prev, previous.AsOf)
+					student.Courses[id] = &course
+				}
+			}
+
+			continue
+		}
+
+		course, prev := student.Courses[f.CourseID], prevStudent.Courses[f.CourseID]
+		if course == nil || prev == nil {
+			continue
+		}
+
+		switch f.Stage {
+		case StageDetails:
+			course.Subject = prev.Subject
+			course.Teacher = prev.Teacher
+			course.Start = prev.Start
+			course.End = prev.End
+			course.Progress = prev.Progress
+			course.Score = prev.Score
+		case StageAssignments:
+			course.Assignments = prev.Assignments
+		}
+
+		course.markStale(prev, previous.AsOf)
+	}
+}
+
+// Fallback wraps reader so that parts of its snapshot that failed to collect
+// are carried over from the data in previous.
+func Fallback(reader, previous Read) Read { return &fallback{reader: reader, previous: previous} }
+
+type fallback struct {
+	reader   Read
+	previous Read
+}
+
+func (f *fallback) Data() (Data, error) { return f.DataContext(context.Background()) }
+
+func (f *fallback) DataContext(ctx context.Context) (Data, error) {
+	data, err := DataContext(ctx, f.reader)
+	if err != nil || len(data.Failures) == 0 {
+		return data, err
+	}
+
+	if prev, err := DataContext(ctx, f.previous); err == nil {
+		data.Carry(prev)
+	}
+
+	return data, nil
+}
+
+func (c *Course) markStale(prev *Course, asOf time.Time) {
+	if c.Stale != nil {
+		return
+	}
+
+	if prev.Stale != nil {
+		asOf = *prev.Stale
+	}
+
+	c.Stale = &asOf
+}
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailureError(t *testing.T) {
+	f := Failure{Student: "Anna &amp; Ben", Course: "Art &amp; Design", Stage: StageDetails, Message: "timeout"}
+
+	if got, want := f.Error(), "Anna & Ben / Art & Design: collecting details: timeout"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+}
+
+// carrySnapshots returns a previous snapshot of a student with two courses,
+// and a later one in which only the first course was listed.
+func carrySnapshots() (Data, Data) {
+	asOf := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	previous := Data{AsOf: asOf, Students: map[int]*Student{1: {ID: 1, Courses: map[int]*Course{
+		7: {
+			ID: 7, StudentID: 1, Title: "Math", Teacher: "Mr. Rivera", Progress: 40, Score: 90,
+			Assignments: map[int]*Assignment{3: {ID: 3, CourseID: 7, StudentID: 1, Status: StatusCompleted}},
+		},
+		8: {ID: 8, StudentID: 1, Title: "Art", Teacher: "Ms. Lee"},
+	}}}}
+
+	current := Data{AsOf: asOf.Add(time.Hour), Students: map[int]*Student{1: {ID: 1, Courses: map[int]*Course{
+		7: {ID: 7, StudentID: 1, Title: "Math", Assignments: map[int]*Assignment{}},
+	}}}}
+
+	return previous, current
+}
+
+func TestCarry(t *testing.T) {
+	previous, _ := carrySnapshots()
+
+	t.Run("courses", func(t *testing.T) {
+		_, d := carrySnapshots()
+		d.Students[1].Courses = nil
+		d.Failures = []Failure{{StudentID: 1, Stage: StageCourses}}
+
+		d.Carry(previous)
+
+		for _, id := range []int{7, 8} {
+			c := d.Students[1].Courses[id]
+			if c == nil || c.Title != previous.Students[1].Courses[id].Title {
+				t.Fatalf("course %d = %+v, want it carried over", id, c)
+			}
+
+			if c.Stale == nil || !c.Stale.Equal(previous.AsOf) {
+				t.Errorf("course %d stale = %v, want %s", id, c.Stale, previous.AsOf)
+			}
+		}
+
+		if previous.Students[1].Courses[8].Stale != nil {
+			t.Error("the previous snapshot was marked stale")
+		}
+	})
+
+	t.Run("details", func(t *testing.T) {
+		_, d := carrySnapshots()
+		d.Failures = []Failure{{StudentID: 1, CourseID: 7, Stage: StageDetails}}
+
+		d.Carry(previous)
+
+		c := d.Students[1].Courses[7]
+		if c.Teacher != "Mr. Rivera" || c.Progress != 40 || c.Score != 90 {
+			t.Errorf("course = %+v, want the previous details", c)
+		}
+
+		if len(c.Assignments) != 0 {
+			t.Errorf("assignments = %v, want the ones collected", c.Assignments)
+		}
+
+		if c.Stale == nil || !c.Stale.Equal(previous.AsOf) {
+			t.Errorf("stale = %v, want %s", c.Stale, previous.AsOf)
+		}
+
+		if _, ok := d.Students[1].Courses[8]; ok {
+			t.Error("carried a course that was not listed")
+		}
+	})
+
+	t.Run("assignments", func(t *testing.T) {
+		_, d := carrySnapshots()
+		d.Failures = []Failure{{StudentID: 1, CourseID: 7, Stage: StageAssignments}}
+
+		d.Carry(previous)
+
+		if c := d.Students[1].Courses[7]; len(c.Assignments) != 1 || c.Assignments[3] == nil {
+			t.Errorf("assignments = %v, want the previous ones", c.Assignments)
+		}
+	})
+
+	t.Run("already stale", func(t *testing.T) {
+		stale := previous.AsOf.Add(-24 * time.Hour)
+
+		prev, d := carrySnapshots()
+		prev.Students[1].Courses[7].Stale = &stale
+		d.Failures = []Failure{{StudentID: 1, CourseID: 7, Stage: StageAssignments}}
+
+		d.Carry(prev)
+
+		// The course is as old as the last time it was collected.
+		if c := d.Students[1].Courses[7]; c.Stale == nil || !c.Stale.Equal(stale) {
+			t.Errorf("stale = %v, want %s", c.Stale, stale)
+		}
+	})
+
+	t.Run("no previous value", func(t *testing.T) {
+		_, d := carrySnapshots()
+		d.Students[2] = &Student{ID: 2}
+		d.Failures = []Failure{
+			{StudentID: 1, CourseID: 9, Stage: StageDetails},
+			{StudentID: 2, Stage: StageCourses},
+		}
+
+		d.Carry(previous)
+
+		if c := d.Students[1].Courses[7]; c.Stale != nil {
+			t.Errorf("course 7 stale = %v, want it left alone", c.Stale)
+		}
+
+		if len(d.Students[2].Courses) != 0 {
+			t.Errorf("student 2 courses = %v, want none", d.Students[2].Courses)
+		}
+	})
+}
+
+type reader struct {
+	data Data
+	err  error
+}
+
+func (r reader) Data() (Data, error) { return r.data, r.err }
+
+func TestFallback(t *testing.T) {
+	previous, current := carrySnapshots()
+	current.Failures = []Failure{{StudentID: 1, CourseID: 7, Stage: StageAssignments}}
+
+	d, err := Fallback(reader{data: current}, reader{data: previous}).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := d.Students[1].Courses[7]; len(c.Assignments) != 1 || c.Stale == nil {
+		t.Errorf("course = %+v, want the previous assignments, marked stale", c)
+	}
+
+	// Without a previous snapshot the failure is left as it is.
+	_, current = carrySnapshots()
+	current.Failures = []Failure{{StudentID: 1, CourseID: 7, Stage: StageAssignments}}
+
+	d, err = Fallback(reader{data: current}, reader{err: ErrNoSnapshot}).Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := d.Students[1].Courses[7]; len(c.Assignments) != 0 || c.Stale != nil {
+		t.Errorf("course = %+v, want it as collected", c)
+	}
+
+	errCollect := errors.New("portal down")
+	if _, err = Fallback(reader{err: errCollect}, reader{data: previous}).Data(); !errors.Is(err, errCollect) {
+		t.Errorf("err = %v, want the collection error", err)
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+// Filter returns a copy of d with only the assignments keep accepts, and only
+// the courses and students left with any. The courses remember all of their
+// assignments, so that their grades, targets and pace are unchanged.
+func (d Data) Filter(keep func(*Student, *Course, *Assignment) bool) Data {
+	filtered := d
+	filtered.Students = map[int]*Student{}
+
+	for id, student := range d.Students {
+		courses := map[int]*Course{}
+
+		for courseID, course := range student.Courses {
+			assignments := map[int]*Assignment{}
+
+			for assignmentID, assignment := range course.Assignments {
+				if keep(student, course, assignment) {
+					assignments[assignmentID] = assignment
+				}
+			}
+
+			if len(assignments) > 0 {
+				filteredCourse := *course
+				filteredCourse.Assignments = assignments
+				filteredCourse.whole = course.all()
+				courses[courseID] = &filteredCourse
+			}
+		}
+
+		if len(courses) > 0 {
+			filteredStudent := *student
+			filteredStudent.Courses = courses
+			filtered.Students[id] = &filteredStudent
+		}
+	}
+
+	return filtered
+}
+
+// all returns the course with all of its assignments, if c was filtered.
+func (c *Course) all() *Course {
+	if c.whole != nil {
+		return c.whole
+	}
+
+	return c
+}
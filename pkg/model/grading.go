@@ -0,0 +1,213 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Grading turns the scores of graded assignments into course grades and a
+// grade point average.
+type Grading struct {
+	// Weights is how much each type of assignment counts towards a course
+	// grade: the average score of each type counts in proportion to its
+	// weight. Types without a weight are left out.
+	Weights map[AssignmentType]float64
+	// Scale lists the letter grades from highest to lowest; a score earns
+	// the first letter whose minimum it reaches, or the last one.
+	Scale []Letter
+}
+
+// Letter is a letter grade and the grade points it is worth.
+type Letter struct {
+	Letter string  `json:"letter"`
+	Min    float64 `json:"min"`
+	Points float64 `json:"points"`
+}
+
+// Grade is a course grade.
+type Grade struct {
+	// Score is the weighted average score, out of 100.
+	Score float64
+	Letter
+	// Graded is how many assignments the grade is based on.
+	Graded int
+}
+
+func (g Grade) String() string { return fmt.Sprintf("%s (%.1f%%)", g.Letter.Letter, g.Score) }
+
+// GPA is a student's grade point average over their graded courses.
+type GPA struct {
+	Points  float64
+	Courses int
+}
+
+func (g GPA) String() string { return fmt.Sprintf("%.2f", g.Points) }
+
+// DefaultGrading returns the grading used unless the policy sets another:
+// every known type weighs the same, on a ten point scale from A to F.
+func DefaultGrading() Grading {
+	weights := map[AssignmentType]float64{}
+	for _, t := range assignmentTypes {
+		weights[t] = 1
+	}
+
+	return Grading{
+		Weights: weights,
+		Scale: []Letter{
+			{Letter: "A", Min: 90, Points: 4},
+			{Letter: "B", Min: 80, Points: 3},
+			{Letter: "C", Min: 70, Points: 2},
+			{Letter: "D", Min: 60, Points: 1},
+			{Letter: "F", Min: 0, Points: 0},
+		},
+	}
+}
+
+// IsGraded reports whether the assignment has a score that counts: it is
+// graded, or complete with a score. Skipped work is never graded.
+func (a *Assignment) IsGraded() bool {
+	switch a.Status {
+	case StatusGraded:
+		return true
+	case StatusCompleted:
+		return a.Score > 0
+	default:
+		return false
+	}
+}
+
+// Grade returns the course's grade under the current policy, or nil if none
+// of its assignments count towards one.
+func (c *Course) Grade() *Grade { return CurrentPolicy().Grading.Grade(c) }
+
+// GPA returns the student's grade point average under the current policy, or
+// nil if none of their courses has a grade.
+func (s *Student) GPA() *GPA { return CurrentPolicy().Grading.GPA(s) }
+
+// Grade returns the weighted average score of the graded assignments in c,
+// or nil if none of them has a weight.
+func (g Grading) Grade(c *Course) *Grade {
+	var (
+		totals = map[AssignmentType]float64{}
+		counts = map[AssignmentType]int{}
+		graded int
+	)
+
+	for _, a := range c.all().Assignments {
+		if !a.IsGraded() || g.Weights[a.Type] <= 0 {
+			continue
+		}
+
+		totals[a.Type] += float64(a.Score)
+		counts[a.Type]++
+		graded++
+	}
+
+	if graded == 0 {
+		return nil
+	}
+
+	var score, weight float64
+	for t, n := range counts {
+		score += g.Weights[t] * totals[t] / float64(n)
+		weight += g.Weights[t]
+	}
+
+	score /= weight
+
+	return &Grade{Score: score, Letter: g.Letter(score), Graded: graded}
+}
+
+// Letter returns the letter grade for score.
+func (g Grading) Letter(score float64) Letter {
+	for _, letter := range g.Scale {
+		if score >= letter.Min {
+			return letter
+		}
+	}
+
+	if len(g.Scale) == 0 {
+		return Letter{}
+	}
+
+	return g.Scale[len(g.Scale)-1]
+}
+
+// GPA returns the average grade points of the courses of s that have a
+// grade, or nil if none does.
+func (g Grading) GPA(s *Student) *GPA {
+	var gpa GPA
+
+	for _, c := range s.Courses {
+		if grade := g.Grade(c); grade != nil {
+			gpa.Points += grade.Points
+			gpa.Courses++
+		}
+	}
+
+	if gpa.Courses == 0 {
+		return nil
+	}
+
+	gpa.Points /= float64(gpa.Courses)
+
+	return &gpa
+}
+
+type gradingJSON struct {
+	Weights map[AssignmentType]float64 `json:"weights"`
+	Scale   []Letter                   `json:"scale"`
+}
+
+// UnmarshalJSON replaces the weights and the scale, if present in b, as
+// described for LoadPolicy.
+func (g *Grading) UnmarshalJSON(b []byte) error {
+	var v gradingJSON
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	if v.Weights != nil {
+		var total float64
+
+		for t, w := range v.Weights {
+			if err := checkType(t); err != nil {
+				return fmt.Errorf("weights: %w", err)
+			}
+
+			if w < 0 {
+				return fmt.Errorf("weight of %q must not be negative, got %g", t, w)
+			}
+
+			total += w
+		}
+
+		if total == 0 {
+			return fmt.Errorf("at least one weight must be positive")
+		}
+
+		g.Weights = v.Weights
+	}
+
+	if v.Scale != nil {
+		if len(v.Scale) == 0 {
+			return fmt.Errorf("scale must have at least one letter")
+		}
+
+		for i := 1; i < len(v.Scale); i++ {
+			if v.Scale[i].Min >= v.Scale[i-1].Min {
+				return fmt.Errorf("scale must go from highest to lowest, but %q is not below %q",
+					v.Scale[i].Letter, v.Scale[i-1].Letter)
+			}
+		}
+
+		g.Scale = v.Scale
+	}
+
+	return nil
+}
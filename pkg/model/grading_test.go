@@ -0,0 +1,117 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gradedCourse returns a course of the given assignments, numbered in order.
+func gradedCourse(assignments ...Assignment) *Course {
+	c := &Course{ID: 7, Title: "Math", Assignments: map[int]*Assignment{}}
+	for i := range assignments {
+		a := assignments[i]
+		a.ID = i + 1
+		c.Assignments[a.ID] = &a
+	}
+
+	return c
+}
+
+func weighted() Grading {
+	g := DefaultGrading()
+	g.Weights = map[AssignmentType]float64{TypeLesson: 20, TypeQuiz: 30, TypeTest: 50}
+
+	return g
+}
+
+func TestGradingGrade(t *testing.T) {
+	tests := map[string]struct {
+		c    *Course
+		want *Grade
+	}{
+		"weighted by type": {
+			gradedCourse(
+				Assignment{Type: TypeLesson, Score: 80, Status: StatusGraded},
+				Assignment{Type: TypeLesson, Score: 100, Status: StatusCompleted},
+				Assignment{Type: TypeQuiz, Score: 70, Status: StatusGraded},
+				Assignment{Type: TypeTest, Status: StatusNotStarted},
+			),
+			&Grade{Score: 78, Letter: Letter{Letter: "C", Min: 70, Points: 2}, Graded: 3},
+		},
+		"ungraded work left out": {
+			gradedCourse(
+				Assignment{Type: TypeTest, Score: 95, Status: StatusGraded},
+				Assignment{Type: TypeTest, Status: StatusCompleted},
+				Assignment{Type: TypeTest, Score: 10, Status: StatusSkipped},
+				Assignment{Type: TypeTest, Score: 10, Status: StatusInProgress},
+				Assignment{Type: TypeProject, Score: 10, Status: StatusGraded},
+			),
+			&Grade{Score: 95, Letter: Letter{Letter: "A", Min: 90, Points: 4}, Graded: 1},
+		},
+		"nothing graded": {
+			gradedCourse(Assignment{Type: TypeQuiz, Status: StatusNotStarted}),
+			nil,
+		},
+	}
+
+	for name, tc := range tests {
+		if got := weighted().Grade(tc.c); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: grade %+v, want %+v", name, got, tc.want)
+		}
+	}
+}
+
+func TestGradingLetter(t *testing.T) {
+	g := DefaultGrading()
+
+	for score, want := range map[float64]string{100: "A", 90: "A", 89.99: "B", 60: "D", 59.9: "F", -5: "F"} {
+		if got := g.Letter(score).Letter; got != want {
+			t.Errorf("Letter(%g) = %q, want %q", score, got, want)
+		}
+	}
+
+	if got := (Grading{}).Letter(90); got != (Letter{}) {
+		t.Errorf("Letter without a scale = %+v", got)
+	}
+}
+
+func TestGradingGPA(t *testing.T) {
+	s := &Student{ID: 1, DisplayName: "Anna", Courses: map[int]*Course{
+		7: gradedCourse(Assignment{Type: TypeQuiz, Score: 92, Status: StatusGraded}),
+		8: gradedCourse(Assignment{Type: TypeQuiz, Score: 75, Status: StatusGraded}),
+		9: gradedCourse(Assignment{Type: TypeQuiz, Status: StatusNotStarted}),
+	}}
+
+	if got, want := weighted().GPA(s), (&GPA{Points: 3, Courses: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("GPA %+v, want %+v", got, want)
+	}
+
+	if got := weighted().GPA(&Student{}); got != nil {
+		t.Errorf("GPA without courses = %+v, want nil", got)
+	}
+}
+
+func TestGradingUnmarshalJSON(t *testing.T) {
+	g := DefaultGrading()
+	if err := g.UnmarshalJSON([]byte(`{"weights": {"Test": 2, "Quiz": 1}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Weights) != 2 || g.Weights[TypeTest] != 2 || len(g.Scale) != 5 {
+		t.Errorf("grading = %+v", g)
+	}
+
+	errs := map[string]string{
+		`{"weights": {"Test": 0}}`: "at least one weight must be positive",
+		`{"scale": [{"letter": "P", "min": 50}, {"letter": "H", "min": 80}]}`: `"H" is not below "P"`,
+		`{"curve": 5}`: "unknown field",
+	}
+
+	for in, want := range errs {
+		g := DefaultGrading()
+		if err := g.UnmarshalJSON([]byte(in)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", in, err, want)
+		}
+	}
+}
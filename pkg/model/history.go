@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoSnapshot = errors.New("no snapshot available")
+	ErrBadTime    = errors.New("unrecognized time")
+)
+
+// History is implemented by backends that keep every saved snapshot.
+type History interface {
+	Snapshots() ([]time.Time, error)
+	DataAsOf(time.Time) (Data, error)
+}
+
+// Rewriter is implemented by histories that can correct a saved snapshot,
+// such as one rebuilt from the portal's archived responses.
+type Rewriter interface {
+	// Replace stores d in place of the snapshot taken at d.AsOf, adding it
+	// to the history if there is none.
+	Replace(d Data) error
+}
+
+// ContextHistory is implemented by histories that can abandon a lookup once
+// ctx is done.
+type ContextHistory interface {
+	DataAsOfContext(ctx context.Context, when time.Time) (Data, error)
+}
+
+// DataAsOfContext looks up the snapshot as of when, through DataAsOfContext
+// when history implements ContextHistory.
+func DataAsOfContext(ctx context.Context, history History, when time.Time) (Data, error) {
+	if h, ok := history.(ContextHistory); ok {
+		return h.DataAsOfContext(ctx, when)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Data{AsOf: when}, err
+	}
+
+	return history.DataAsOf(when)
+}
+
+// Digest fingerprints the content of a snapshot, ignoring when it was taken,
+// so that identical consecutive snapshots can be detected.
+func Digest(d Data) (string, error) {
+	d.AsOf = time.Time{}
+	d.Errors = nil
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ParseAsOf parses a point in time given on the command line or in a query
+// string. A bare date refers to the end of that day.
+func ParseAsOf(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w %q", ErrBadTime, s)
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"log"
 	"sort"
 	"time"
@@ -31,6 +32,102 @@ type Write interface {
 	Save(Read) error
 }
 
+// ContextReader is implemented by readers that can abandon a read once ctx
+// is done.
+type ContextReader interface {
+	DataContext(ctx context.Context) (Data, error)
+}
+
+// ContextWriter is implemented by writers that can abandon a save once ctx is
+// done.
+type ContextWriter interface {
+	SaveContext(ctx context.Context, reader Read) error
+}
+
+// ContextResetter is implemented by backends that can abandon a reset once
+// ctx is done.
+type ContextResetter interface {
+	ResetContext(ctx context.Context) error
+}
+
+// DataContext reads from reader, through DataContext when reader implements
+// ContextReader. Otherwise it stops waiting for Data once ctx is done, leaving
+// the read to finish in the background.
+func DataContext(ctx context.Context, reader Read) (Data, error) {
+	if r, ok := reader.(ContextReader); ok {
+		return r.DataContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Data{AsOf: time.Now()}, err
+	}
+
+	type result struct {
+		data Data
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		data, err := reader.Data()
+		done <- result{data: data, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		return Data{AsOf: time.Now()}, ctx.Err()
+	}
+}
+
+// SaveContext saves the data from reader to writer, through SaveContext when
+// writer implements ContextWriter. Otherwise only the read honors ctx.
+func SaveContext(ctx context.Context, writer Write, reader Read) error {
+	if w, ok := writer.(ContextWriter); ok {
+		return w.SaveContext(ctx, reader)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return writer.Save(&contextReader{ctx: ctx, reader: reader})
+}
+
+// ResetContext resets backend, through ResetContext when backend implements
+// ContextResetter.
+func ResetContext(ctx context.Context, backend Full) error {
+	if r, ok := backend.(ContextResetter); ok {
+		return r.ResetContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return backend.Reset()
+}
+
+// contextReader binds a context to a reader that is passed on as a Read.
+type contextReader struct {
+	ctx    context.Context
+	reader Read
+}
+
+func (c *contextReader) Data() (Data, error) { return DataContext(c.ctx, c.reader) }
+
+func (c *contextReader) DataContext(ctx context.Context) (Data, error) {
+	return DataContext(ctx, c.reader)
+}
+
+// Watcher is implemented by backends that can push each snapshot as it is
+// saved. Watch calls the function with the current data, then again on every
+// update, until stop is called.
+type Watcher interface {
+	Watch(func(Data)) (stop func(), err error)
+}
+
 type Full interface {
 	Read
 	Write
@@ -41,6 +138,7 @@ type Full interface {
 type Data struct {
 	Students map[int]*Student `json:"students"`
 	Errors   []error          `json:"errors"`
+	Failures []Failure        `json:"failures,omitempty"`
 	AsOf     time.Time        `json:"as_of"`
 }
 
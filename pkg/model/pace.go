@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Pace compares how much of a course is done with how much should be.
+type Pace struct {
+	// Expected is the fraction of the course's assignments that should be
+	// done by the end of the day.
+	Expected float64
+	// Actual is the fraction that is done.
+	Actual float64
+	// Assignments is how many assignments ahead of pace, when positive, or
+	// behind it, when negative.
+	Assignments int
+	// Days is how many school days ahead of pace, when positive, or behind
+	// it, when negative.
+	Days int
+	// ByDueDate reports whether the schedule follows the assignments' due
+	// dates, rather than running evenly from the course's start to its end.
+	ByDueDate bool
+}
+
+func (p Pace) String() string {
+	switch {
+	case p.Assignments > 0:
+		return fmt.Sprintf("%s / %s ahead of pace", count(p.Assignments, "assignment"), count(p.Days, "day"))
+	case p.Assignments < 0:
+		return fmt.Sprintf("%s / %s behind pace", count(-p.Assignments, "assignment"), count(-p.Days, "day"))
+	default:
+		return "on pace"
+	}
+}
+
+// Pace returns the course's pace today, or nil if it has no schedule.
+func (c *Course) Pace() *Pace { return c.PaceAt(time.Now()) }
+
+// PaceAt returns the course's pace as of now under the current policy, or nil
+// if it has no schedule.
+func (c *Course) PaceAt(now time.Time) *Pace { return CurrentPolicy().PaceAt(c, now) }
+
+// PaceAt returns the pace of c as of now, or nil if it has no schedule. The
+// schedule follows the due dates of its assignments when they all have one,
+// and otherwise spreads them evenly over the school days from the course's
+// start to its end. Assignments with unknown statuses are left out.
+func (p Policy) PaceAt(c *Course, now time.Time) *Pace {
+	c = c.all()
+
+	var (
+		assignments []*Assignment
+		done        int
+		byDueDate   = true
+	)
+
+	for _, a := range c.Assignments {
+		if !a.Status.Known() {
+			continue
+		}
+
+		assignments = append(assignments, a)
+
+		if !p.isOpen(a) {
+			done++
+		}
+
+		if a.DueDate().IsZero() {
+			byDueDate = false
+		}
+	}
+
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	targets := p.targets(c, assignments, byDueDate)
+	if targets == nil {
+		return nil
+	}
+
+	today := DayOf(now)
+	due := sort.Search(len(targets), func(i int) bool { return !targets[i].Before(today) })
+	through := sort.Search(len(targets), func(i int) bool { return targets[i].After(today) })
+
+	pace := &Pace{
+		Expected:  float64(through) / float64(len(targets)),
+		Actual:    float64(done) / float64(len(targets)),
+		ByDueDate: byDueDate,
+	}
+
+	// Work due today is on pace whether or not it is done yet.
+	switch {
+	case done < due:
+		pace.Assignments = done - due
+		pace.Days = -p.Calendar.SchoolDaysBetween(targets[done], today)
+	case done > through:
+		pace.Assignments = done - through
+		pace.Days = p.Calendar.SchoolDaysBetween(today, targets[done-1])
+	}
+
+	return pace
+}
+
+// targets returns, in order, the day by which each of the assignments should
+// be done, or nil if there is no schedule for them.
+func (p Policy) targets(c *Course, assignments []*Assignment, byDueDate bool) []time.Time {
+	targets := make([]time.Time, 0, len(assignments))
+
+	if byDueDate {
+		for _, a := range assignments {
+			targets = append(targets, p.dueDay(a))
+		}
+
+		sort.Slice(targets, func(x, y int) bool { return targets[x].Before(targets[y]) })
+
+		return targets
+	}
+
+	start, end := c.StartDate(), c.EndDate()
+	if start.IsZero() || end.IsZero() {
+		return nil
+	}
+
+	days := p.Calendar.schoolDays(start.AddDate(0, 0, -1), end)
+	if len(days) == 0 {
+		return nil
+	}
+
+	// The k-th of n assignments is due once k/n of the school days are over.
+	for k := 1; k <= len(assignments); k++ {
+		targets = append(targets, days[(k*len(days)+len(assignments)-1)/len(assignments)-1])
+	}
+
+	return targets
+}
+
+func count(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyPaceAt(t *testing.T) {
+	// course returns a course with an assignment due on each of dues, the
+	// first done of them done.
+	course := func(start, end string, done int, dues ...string) *Course {
+		c := &Course{ID: 7, Title: "Math", Start: start, End: end, Assignments: map[int]*Assignment{}}
+		for i, due := range dues {
+			a := &Assignment{ID: i + 1, Unit: 1, Title: due, Due: due, Status: StatusNotStarted}
+			if i < done {
+				a.Status, a.Progress = StatusCompleted, 100
+			}
+
+			c.Assignments[a.ID] = a
+		}
+
+		return c
+	}
+
+	dated := []string{"2022-09-01", "2022-09-05", "2022-09-10", "2022-09-20"}
+	undated := []string{"", "", "", ""}
+
+	tests := map[string]struct {
+		c    *Course
+		now  string
+		want *Pace
+	}{
+		"on pace": {
+			course("", "", 3, dated...), "2022-09-12",
+			&Pace{Expected: 0.75, Actual: 0.75, ByDueDate: true},
+		},
+		"behind": {
+			course("", "", 1, dated...), "2022-09-12",
+			&Pace{Expected: 0.75, Actual: 0.25, Assignments: -2, Days: -7, ByDueDate: true},
+		},
+		"ahead": {
+			course("", "", 4, dated...), "2022-09-12",
+			&Pace{Expected: 0.75, Actual: 1, Assignments: 1, Days: 8, ByDueDate: true},
+		},
+		"due today": {
+			course("", "", 0, "2022-09-12"), "2022-09-12 18:00",
+			&Pace{Expected: 1, Actual: 0, ByDueDate: true},
+		},
+		"spread over the course": {
+			course("2022-09-01", "2022-09-10", 0, undated...), "2022-09-06",
+			&Pace{Expected: 0.5, Actual: 0, Assignments: -2, Days: -3},
+		},
+		"no schedule": {course("", "", 0, undated...), "2022-09-06", nil},
+		"no work":     {course("2022-09-01", "2022-09-10", 0), "2022-09-06", nil},
+	}
+
+	for name, tc := range tests {
+		if got := DefaultPolicy().PaceAt(tc.c, day(tc.now)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: pace %+v, want %+v", name, got, tc.want)
+		}
+	}
+
+	unknown := course("", "", 0, dated...)
+	for _, a := range unknown.Assignments {
+		a.Status = "Archived"
+	}
+
+	if got := DefaultPolicy().PaceAt(unknown, day("2022-09-12")); got != nil {
+		t.Errorf("unknown statuses: pace %+v, want nil", got)
+	}
+}
+
+func TestPaceString(t *testing.T) {
+	tests := map[string]Pace{
+		"on pace":                              {},
+		"1 assignment / 1 day ahead of pace":   {Assignments: 1, Days: 1},
+		"2 assignments / 7 days behind pace":   {Assignments: -2, Days: -7},
+		"3 assignments / 0 days ahead of pace": {Assignments: 3},
+	}
+
+	for want, p := range tests {
+		if got := p.String(); got != want {
+			t.Errorf("%+v: %q, want %q", p, got, want)
+		}
+	}
+}
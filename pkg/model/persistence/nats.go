@@ -1,8 +1,14 @@
 package persistence
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
@@ -28,55 +34,97 @@ func NewNATSModel(conn string) model.Full {
 type NATSModel struct {
 	connURL string
 
-	conn *nats.Conn
-	js   nats.JetStreamContext
-
+	// mu guards the connection and the stores opened on it, which are set
+	// up on first use and replaced by Reset, and the data last read. Saves,
+	// reads of past snapshots and the raw archive run concurrently.
+	mu            sync.Mutex
+	conn          *nats.Conn
+	js            nats.JetStreamContext
 	ignitiaBucket nats.KeyValue
+	historyBucket nats.KeyValue
+	rawStore      nats.ObjectStore
+	data          model.Data
 
-	data model.Data
+	// seeded is set once the history has been seeded, so that later saves
+	// need not list it.
+	seeded bool
 }
 
-func (n *NATSModel) refresh() error {
-	var (
-		err     error
-		entry   nats.KeyValueEntry
-		working model.Data
-	)
+func (n *NATSModel) refresh(ctx context.Context) (model.Data, error) {
+	var working model.Data
 
-	if n.ignitiaBucket == nil {
-		if n.ignitiaBucket, err = n.openOrCreate(ignitiaBucket); err != nil {
-			return err
-		}
+	latest, err := n.latest(ctx)
+	if err != nil {
+		return working, err
 	}
 
-	if entry, err = n.ignitiaBucket.Get(ignitiaKey); err != nil {
-		return err
+	entry, err := kvGet(ctx, latest, ignitiaKey)
+	if err != nil {
+		return working, err
 	}
 
 	if err = json.Unmarshal(entry.Value(), &working); err != nil {
-		return err
+		return working, err
 	}
 
+	n.mu.Lock()
 	n.data = working
+	n.mu.Unlock()
 
-	return nil
+	return working, nil
 }
 
 func (n *NATSModel) Data() (model.Data, error) {
-	if err := n.refresh(); err != nil {
+	return n.DataContext(context.Background())
+}
+
+func (n *NATSModel) DataContext(ctx context.Context) (model.Data, error) {
+	data, err := n.refresh(ctx)
+	if err != nil {
 		return model.Data{Errors: []error{err}, AsOf: time.Now()}, err
 	}
 
-	return n.data, nil
+	return data, nil
+}
+
+func (n *NATSModel) Watch(fn func(model.Data)) (func(), error) {
+	latest, err := n.latest(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := latest.Watch(ignitiaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for entry := range watcher.Updates() {
+			// A nil entry marks the end of the initial values.
+			if entry == nil || entry.Operation() != nats.KeyValuePut {
+				continue
+			}
+
+			var working model.Data
+			if err := json.Unmarshal(entry.Value(), &working); err != nil {
+				continue
+			}
+
+			fn(working)
+		}
+	}()
+
+	return func() { _ = watcher.Stop() }, nil
 }
 
 func (n *NATSModel) Students() []model.Student {
-	if err := n.refresh(); err != nil {
+	data, err := n.refresh(context.Background())
+	if err != nil {
 		return nil
 	}
 
 	var students []model.Student
-	for _, student := range n.data.Students {
+	for _, student := range data.Students {
 		students = append(students, *student)
 	}
 
@@ -84,11 +132,12 @@ func (n *NATSModel) Students() []model.Student {
 }
 
 func (n *NATSModel) Courses(student model.Student) []model.Course {
-	if err := n.refresh(); err != nil {
+	data, err := n.refresh(context.Background())
+	if err != nil {
 		return nil
 	}
 
-	s, ok := n.data.Students[student.ID]
+	s, ok := data.Students[student.ID]
 	if !ok {
 		return nil
 	}
@@ -102,11 +151,12 @@ func (n *NATSModel) Courses(student model.Student) []model.Course {
 }
 
 func (n *NATSModel) Assignments(student model.Student, course model.Course) []model.Assignment {
-	if err := n.refresh(); err != nil {
+	data, err := n.refresh(context.Background())
+	if err != nil {
 		return nil
 	}
 
-	c, ok := n.data.Students[student.ID].Courses[course.ID]
+	c, ok := data.Students[student.ID].Courses[course.ID]
 	if !ok {
 		return nil
 	}
@@ -119,20 +169,144 @@ func (n *NATSModel) Assignments(student model.Student, course model.Course) []mo
 	return assignments
 }
 
+func (n *NATSModel) Snapshots() ([]time.Time, error) {
+	history, err := n.history(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := historyKeys(context.Background(), history)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]time.Time, 0, len(keys))
+	for _, key := range keys {
+		snapshots = append(snapshots, time.Unix(0, key))
+	}
+
+	return snapshots, nil
+}
+
+func (n *NATSModel) DataAsOf(when time.Time) (model.Data, error) {
+	return n.DataAsOfContext(context.Background(), when)
+}
+
+func (n *NATSModel) DataAsOfContext(ctx context.Context, when time.Time) (model.Data, error) {
+	data, err := n.dataAsOf(ctx, when)
+	if err != nil {
+		return model.Data{Errors: []error{err}, AsOf: when}, err
+	}
+
+	return data, nil
+}
+
+func (n *NATSModel) dataAsOf(ctx context.Context, when time.Time) (model.Data, error) {
+	var working model.Data
+
+	history, err := n.history(ctx)
+	if err != nil {
+		return working, err
+	}
+
+	keys, err := historyKeys(ctx, history)
+	if err != nil {
+		return working, err
+	}
+
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] > when.UnixNano() })
+	if i == 0 {
+		return working, model.ErrNoSnapshot
+	}
+
+	entry, err := kvGet(ctx, history, historyKey(time.Unix(0, keys[i-1])))
+	if err != nil {
+		return working, err
+	}
+
+	err = json.Unmarshal(entry.Value(), &working)
+
+	return working, err
+}
+
+// historyKeys returns the snapshot times in the history bucket, in unix
+// nanoseconds, oldest first.
+func historyKeys(ctx context.Context, history nats.KeyValue) ([]int64, error) {
+	keys, err := history.Keys(nats.Context(ctx))
+	if err != nil {
+		if errors.Is(err, nats.ErrNoKeysFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var parsed []int64
+	for _, key := range keys {
+		nanos, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		parsed = append(parsed, nanos)
+	}
+
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i] < parsed[j] })
+
+	return parsed, nil
+}
+
+func historyKey(asOf time.Time) string { return strconv.FormatInt(asOf.UnixNano(), 10) }
+
+// Save stores the snapshot as the latest value and, unless its content is
+// unchanged, in the history. Assignment events relative to the previous
+// snapshot are then published on the events stream.
 func (n *NATSModel) Save(reader model.Read) error {
+	return n.SaveContext(context.Background(), reader)
+}
+
+func (n *NATSModel) SaveContext(ctx context.Context, reader model.Read) error {
 	var (
-		err  error
-		s    model.Data
-		data []byte
+		err      error
+		s        model.Data
+		previous model.Data
+		data     []byte
+		same     bool
+		seen     bool
 	)
 
-	if n.ignitiaBucket == nil {
-		if n.ignitiaBucket, err = n.openOrCreate(ignitiaBucket); err != nil {
+	latest, err := n.latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	history, err := n.history(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s, err = model.DataContext(ctx, reader); err != nil {
+		return err
+	}
+
+	entry, err := kvGet(ctx, latest, ignitiaKey)
+	switch {
+	case err == nil:
+		if err = json.Unmarshal(entry.Value(), &previous); err != nil {
+			return err
+		}
+
+		if err = n.seedHistory(ctx, history, previous.AsOf, entry.Value()); err != nil {
 			return err
 		}
-	}
 
-	if s, err = reader.Data(); err != nil {
+		if same, err = sameContent(previous, s); err != nil {
+			return err
+		}
+
+		seen = true
+	case errors.Is(err, nats.ErrKeyNotFound):
+	default:
 		return err
 	}
 
@@ -140,21 +314,362 @@ func (n *NATSModel) Save(reader model.Read) error {
 		return err
 	}
 
-	_, err = n.ignitiaBucket.Put(ignitiaKey, data)
+	// Puts cannot be abandoned midway, so the save stops short of them once
+	// ctx is done.
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if !same {
+		if _, err = history.Put(historyKey(s.AsOf), data); err != nil {
+			return err
+		}
+	}
+
+	// The latest value is written even when unchanged so that it records
+	// when it was last checked, which keeps became-overdue events from
+	// repeating.
+	if _, err = latest.Put(ignitiaKey, data); err != nil {
+		return err
+	}
+
+	if !seen {
+		return nil
+	}
+
+	return n.publish(ctx, model.Events(previous, s))
+}
+
+// seedHistory carries over a snapshot written before history was kept, the
+// first time it is replaced. The history is only listed on the first save.
+func (n *NATSModel) seedHistory(ctx context.Context, history nats.KeyValue, asOf time.Time, value []byte) error {
+	n.mu.Lock()
+	seeded := n.seeded
+	n.mu.Unlock()
+
+	if seeded {
+		return nil
+	}
+
+	keys, err := historyKeys(ctx, history)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		if _, err = history.Put(historyKey(asOf), value); err != nil {
+			return err
+		}
+	}
+
+	n.mu.Lock()
+	n.seeded = true
+	n.mu.Unlock()
+
+	return nil
+}
+
+func (n *NATSModel) publish(ctx context.Context, events []model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	js, err := n.jetStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = ensureEventStream(ctx, js); err != nil {
+		return err
+	}
+
+	var errs error
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		subject := event.Subject(eventPrefix)
+		msgID := nats.MsgId(fmt.Sprintf("%s@%d", subject, event.AsOf.UnixNano()))
+		if _, err = js.Publish(subject, data, msgID, nats.Context(ctx)); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func ensureEventStream(ctx context.Context, js nats.JetStreamContext) error {
+	_, err := js.StreamInfo(eventStream, nats.Context(ctx))
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     eventStream,
+			Subjects: []string{eventPrefix + ".>"},
+		}, nats.Context(ctx))
+	}
+
+	return err
+}
+
+func sameContent(lhs, rhs model.Data) (bool, error) {
+	l, err := model.Digest(lhs)
+	if err != nil {
+		return false, err
+	}
+
+	r, err := model.Digest(rhs)
+	if err != nil {
+		return false, err
+	}
+
+	return l == r, nil
+}
+
+// Replace stores d in place of the snapshot taken at d.AsOf, adding it if
+// there is none, and as the latest value too when that is the snapshot it
+// replaces. No events are published for the correction.
+func (n *NATSModel) Replace(d model.Data) error {
+	ctx := context.Background()
+
+	latest, err := n.latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	history, err := n.history(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if _, err = history.Put(historyKey(d.AsOf), data); err != nil {
+		return err
+	}
+
+	entry, err := latest.Get(ignitiaKey)
+	switch {
+	case err == nil:
+	case errors.Is(err, nats.ErrKeyNotFound):
+		return nil
+	default:
+		return err
+	}
+
+	var current model.Data
+	if err = json.Unmarshal(entry.Value(), &current); err != nil {
+		return err
+	}
+
+	if !current.AsOf.Equal(d.AsOf) {
+		return nil
+	}
+
+	_, err = latest.Put(ignitiaKey, data)
+
+	return err
+}
+
+// LoadCookies returns the portal cookies saved by SaveCookies, if any.
+func (n *NATSModel) LoadCookies() ([]byte, error) {
+	latest, err := n.latest(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := latest.Get(cookiesKey)
+	switch {
+	case err == nil:
+		return entry.Value(), nil
+	case errors.Is(err, nats.ErrKeyNotFound):
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// SaveCookies keeps the already encrypted portal cookies for the next run.
+func (n *NATSModel) SaveCookies(value []byte) error {
+	latest, err := n.latest(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = latest.Put(cookiesKey, value)
+
+	return err
+}
+
+// PutRaw archives a raw portal response collected for the snapshot taken at
+// asOf, in the raw object store.
+func (n *NATSModel) PutRaw(asOf time.Time, name string, body []byte) error {
+	store, err := n.raw()
+	if err != nil {
+		return err
+	}
+
+	_, err = store.PutBytes(rawName(asOf, name), body)
+
 	return err
 }
 
+// GetRaw returns a raw portal response archived by PutRaw.
+func (n *NATSModel) GetRaw(asOf time.Time, name string) ([]byte, error) {
+	store, err := n.raw()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := store.GetBytes(rawName(asOf, name))
+	if errors.Is(err, nats.ErrObjectNotFound) {
+		return nil, fmt.Errorf("%s for %s: %w", name, asOf.Format(time.RFC3339Nano), err)
+	}
+
+	return body, err
+}
+
+// RawSnapshots returns the times of the snapshots with archived responses,
+// oldest first.
+func (n *NATSModel) RawSnapshots() ([]time.Time, error) {
+	store, err := n.raw()
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := store.List()
+	if err != nil {
+		if errors.Is(err, nats.ErrNoObjectsFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var (
+		snapshots []time.Time
+		seen      = map[int64]bool{}
+	)
+
+	for _, object := range objects {
+		prefix, _, found := strings.Cut(object.Name, "/")
+		if !found {
+			continue
+		}
+
+		nanos, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || seen[nanos] {
+			continue
+		}
+
+		seen[nanos] = true
+		snapshots = append(snapshots, time.Unix(0, nanos))
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Before(snapshots[j]) })
+
+	return snapshots, nil
+}
+
+// DeleteRaw drops the raw portal responses archived for the snapshot taken
+// at asOf.
+func (n *NATSModel) DeleteRaw(asOf time.Time) error {
+	store, err := n.raw()
+	if err != nil {
+		return err
+	}
+
+	objects, err := store.List()
+	if err != nil {
+		if errors.Is(err, nats.ErrNoObjectsFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	prefix := historyKey(asOf) + "/"
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Name, prefix) {
+			continue
+		}
+
+		if err = store.Delete(object.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *NATSModel) raw() (nats.ObjectStore, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.rawStore != nil {
+		return n.rawStore, nil
+	}
+
+	if err := n.reset(context.Background()); err != nil {
+		return nil, err
+	}
+
+	store, err := n.js.ObjectStore(rawBucket)
+	if err != nil {
+		if store, err = n.js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: rawBucket}); err != nil {
+			return nil, err
+		}
+	}
+
+	n.rawStore = store
+
+	return store, nil
+}
+
+func rawName(asOf time.Time, name string) string { return historyKey(asOf) + "/" + name }
+
 func (n *NATSModel) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.close()
+
+	return nil
+}
+
+// close drops the connection and the stores opened on it; n.mu must be held.
+func (n *NATSModel) close() {
 	n.conn.Close()
 	n.conn = nil
 	n.js = nil
-
-	return nil
+	n.ignitiaBucket = nil
+	n.historyBucket = nil
+	n.rawStore = nil
 }
 
 func (n *NATSModel) Reset() error {
+	return n.ResetContext(context.Background())
+}
+
+func (n *NATSModel) ResetContext(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.reset(ctx)
+}
+
+// reset connects, unless already connected; n.mu must be held.
+func (n *NATSModel) reset(ctx context.Context) error {
 	var err error
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	n.data = model.Data{AsOf: time.Now()}
 
 	if n.conn != nil {
@@ -162,12 +677,15 @@ func (n *NATSModel) Reset() error {
 			return nil
 		}
 
-		if err = n.Close(); err != nil {
-			return err
-		}
+		n.close()
+	}
+
+	var opts []nats.Option
+	if deadline, ok := ctx.Deadline(); ok {
+		opts = append(opts, nats.Timeout(time.Until(deadline)))
 	}
 
-	if n.conn, err = nats.Connect(n.connURL); err != nil {
+	if n.conn, err = nats.Connect(n.connURL, opts...); err != nil {
 		return err
 	}
 
@@ -179,6 +697,9 @@ func (n *NATSModel) Reset() error {
 }
 
 func (n *NATSModel) Error() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
 	if len(n.data.Errors) > 0 {
 		return multierror.Append(nil, n.data.Errors...)
 	}
@@ -189,20 +710,83 @@ func (n *NATSModel) Error() error {
 const (
 	ignitiaBucket = "ignitia"
 	ignitiaKey    = "simeon"
+	cookiesKey    = "cookies"
+	historyBucket = "ignitia-history"
+	rawBucket     = "ignitia-raw"
+	eventStream   = "IGNITIA_EVENTS"
+	eventPrefix   = "ignitia"
 )
 
-func (n *NATSModel) openOrCreate(bucket string) (nats.KeyValue, error) {
-	if err := n.Reset(); err != nil {
+// latest returns the bucket holding the latest snapshot and the cookies.
+func (n *NATSModel) latest(ctx context.Context) (nats.KeyValue, error) {
+	return n.bucket(ctx, &n.ignitiaBucket, ignitiaBucket)
+}
+
+// history returns the bucket holding every snapshot by time.
+func (n *NATSModel) history(ctx context.Context) (nats.KeyValue, error) {
+	return n.bucket(ctx, &n.historyBucket, historyBucket)
+}
+
+// bucket returns the bucket kept in *kv, opening or creating the named bucket
+// the first time.
+func (n *NATSModel) bucket(ctx context.Context, kv *nats.KeyValue, name string) (nats.KeyValue, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if *kv != nil {
+		return *kv, nil
+	}
+
+	if err := n.reset(ctx); err != nil {
 		return nil, err
 	}
 
-	kv, err := n.js.KeyValue(bucket)
+	bucket, err := n.js.KeyValue(name)
 	if err != nil {
-		kv, err = n.js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket})
-		if err != nil {
+		if bucket, err = n.js.CreateKeyValue(&nats.KeyValueConfig{Bucket: name}); err != nil {
 			return nil, err
 		}
 	}
 
-	return kv, nil
+	*kv = bucket
+
+	return bucket, nil
+}
+
+// jetStream returns the JetStream context of the connection.
+func (n *NATSModel) jetStream(ctx context.Context) (nats.JetStreamContext, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.reset(ctx); err != nil {
+		return nil, err
+	}
+
+	return n.js, nil
+}
+
+// kvGet reads key from bucket, giving up once ctx is done since key value reads
+// only honor the JetStream request timeout.
+func kvGet(ctx context.Context, bucket nats.KeyValue, key string) (nats.KeyValueEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		entry nats.KeyValueEntry
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		entry, err := bucket.Get(key)
+		done <- result{entry: entry, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.entry, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
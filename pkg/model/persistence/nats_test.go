@@ -0,0 +1,296 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// startNATS runs an embedded JetStream server for the length of the test and
+// returns its client URL.
+func startNATS(t *testing.T) string {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go srv.Start()
+
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+
+	t.Cleanup(srv.Shutdown)
+
+	return srv.ClientURL()
+}
+
+func newNATS(t *testing.T) (*NATSModel, string) {
+	t.Helper()
+
+	url := startNATS(t)
+	n := NewNATSModel(url).(*NATSModel)
+	t.Cleanup(func() { _ = n.Close() })
+
+	return n, url
+}
+
+type snapshot model.Data
+
+func (s snapshot) Data() (model.Data, error) { return model.Data(s), nil }
+
+func testData(asOf time.Time, status model.Status) model.Data {
+	return model.Data{
+		AsOf: asOf,
+		Students: map[int]*model.Student{1: {ID: 1, DisplayName: "Anna", Courses: map[int]*model.Course{
+			7: {ID: 7, StudentID: 1, Title: "Math", Assignments: map[int]*model.Assignment{
+				3: {ID: 3, CourseID: 7, StudentID: 1, Title: "Fractions", Type: model.TypeLesson, Status: status, Due: "2999-01-01"},
+			}},
+		}}},
+	}
+}
+
+func TestNATSSaveKeepsHistory(t *testing.T) {
+	n, _ := newNATS(t)
+
+	first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	steps := []struct {
+		asOf   time.Time
+		status model.Status
+	}{
+		{first, model.StatusNotStarted},
+		{first.Add(time.Hour), model.StatusNotStarted},
+		{first.Add(2 * time.Hour), model.StatusCompleted},
+	}
+
+	for _, step := range steps {
+		if err := n.Save(snapshot(testData(step.asOf, step.status))); err != nil {
+			t.Fatalf("saving %s: %v", step.asOf, err)
+		}
+	}
+
+	snapshots, err := n.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The unchanged second snapshot is not kept.
+	if len(snapshots) != 2 || !snapshots[0].Equal(first) || !snapshots[1].Equal(first.Add(2*time.Hour)) {
+		t.Fatalf("snapshots = %v", snapshots)
+	}
+
+	old, err := n.DataAsOf(first.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := old.Students[1].Courses[7].Assignments[3].Status; got != model.StatusNotStarted {
+		t.Errorf("status as of 09:30 = %q, want %q", got, model.StatusNotStarted)
+	}
+
+	if _, err := n.DataAsOf(first.Add(-time.Hour)); !errors.Is(err, model.ErrNoSnapshot) {
+		t.Errorf("data before the first snapshot: err = %v, want ErrNoSnapshot", err)
+	}
+
+	latest, err := n.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := latest.Students[1].Courses[7].Assignments[3].Status; got != model.StatusCompleted {
+		t.Errorf("latest status = %q, want %q", got, model.StatusCompleted)
+	}
+}
+
+func TestNATSSeedsHistory(t *testing.T) {
+	n, _ := newNATS(t)
+
+	// A snapshot saved before history was kept is only the latest value.
+	first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	latest, err := n.latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := json.Marshal(testData(first, model.StatusNotStarted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = latest.Put(ignitiaKey, old); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, asOf := range []time.Time{first.Add(time.Hour), first.Add(2 * time.Hour)} {
+		if err = n.Save(snapshot(testData(asOf, model.StatusCompleted))); err != nil {
+			t.Fatalf("saving %s: %v", asOf, err)
+		}
+
+		if !n.seeded {
+			t.Fatalf("history not marked seeded after saving %s", asOf)
+		}
+	}
+
+	snapshots, err := n.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The unchanged last save adds nothing, and in particular is not seeded.
+	if len(snapshots) != 2 || !snapshots[0].Equal(first) || !snapshots[1].Equal(first.Add(time.Hour)) {
+		t.Fatalf("snapshots = %v", snapshots)
+	}
+}
+
+func TestNATSSavePublishesEvents(t *testing.T) {
+	n, url := newNATS(t)
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	if err := n.Save(snapshot(testData(first, model.StatusNotStarted))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.Save(snapshot(testData(first.Add(time.Hour), model.StatusCompleted))); err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := js.SubscribeSync(eventPrefix+".>", nats.DeliverAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "ignitia.1.7.assignment.3.completed"; msg.Subject != want {
+		t.Errorf("subject = %q, want %q", msg.Subject, want)
+	}
+
+	if _, err := sub.NextMsg(100 * time.Millisecond); !errors.Is(err, nats.ErrTimeout) {
+		t.Errorf("expected a single event, got err = %v", err)
+	}
+}
+
+func TestNATSWatch(t *testing.T) {
+	n, _ := newNATS(t)
+
+	updates := make(chan model.Data, 1)
+
+	stop, err := n.Watch(func(d model.Data) { updates <- d })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	asOf := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	if err := n.Save(snapshot(testData(asOf, model.StatusNotStarted))); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-updates:
+		if !d.AsOf.Equal(asOf) {
+			t.Errorf("watched as of %s, want %s", d.AsOf, asOf)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update seen")
+	}
+}
+
+func TestNATSCookies(t *testing.T) {
+	n, _ := newNATS(t)
+
+	if got, err := n.LoadCookies(); err != nil || got != nil {
+		t.Fatalf("LoadCookies before saving = %q, %v", got, err)
+	}
+
+	if err := n.SaveCookies([]byte("sealed")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := n.LoadCookies(); err != nil || string(got) != "sealed" {
+		t.Fatalf("LoadCookies = %q, %v", got, err)
+	}
+}
+
+func TestNATSReplace(t *testing.T) {
+	n, _ := newNATS(t)
+
+	asOf := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	if err := n.Save(snapshot(testData(asOf, model.StatusNotStarted))); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed := testData(asOf, model.StatusInProgress)
+	if err := n.Replace(fixed); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, read := range map[string]func() (model.Data, error){
+		"latest":  n.Data,
+		"history": func() (model.Data, error) { return n.DataAsOf(asOf) },
+	} {
+		d, err := read()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if got := d.Students[1].Courses[7].Assignments[3].Status; got != model.StatusInProgress {
+			t.Errorf("%s status = %q, want %q", name, got, model.StatusInProgress)
+		}
+	}
+}
+
+func TestNATSRawArchive(t *testing.T) {
+	n, _ := newNATS(t)
+
+	asOf := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	if err := n.PutRaw(asOf, "students.json", []byte(`[]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := n.GetRaw(asOf, "students.json")
+	if err != nil || string(body) != `[]` {
+		t.Fatalf("GetRaw = %q, %v", body, err)
+	}
+
+	if _, err := n.GetRaw(asOf, "missing.json"); err == nil {
+		t.Error("expected an error for a missing response")
+	}
+
+	snapshots, err := n.RawSnapshots()
+	if err != nil || len(snapshots) != 1 || !snapshots[0].Equal(asOf) {
+		t.Fatalf("RawSnapshots = %v, %v", snapshots, err)
+	}
+
+	if err = n.DeleteRaw(asOf); err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshots, err = n.RawSnapshots(); err != nil || len(snapshots) != 0 {
+		t.Fatalf("RawSnapshots after DeleteRaw = %v, %v", snapshots, err)
+	}
+}
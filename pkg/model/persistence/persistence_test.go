@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// TestConcurrentSaveAndRead saves snapshots while others are read, starting
+// from a backend that has not been used yet. Run it with -race.
+func TestConcurrentSaveAndRead(t *testing.T) {
+	nats, _ := newNATS(t)
+
+	backends := map[string]interface {
+		model.Full
+		model.History
+	}{
+		"nats":   nats,
+		"sqlite": newSQLite(t),
+	}
+
+	for name, m := range backends {
+		t.Run(name, func(t *testing.T) {
+			first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+			statuses := []model.Status{model.StatusNotStarted, model.StatusInProgress, model.StatusCompleted}
+
+			var wg sync.WaitGroup
+
+			errs := make(chan error, 4*len(statuses))
+
+			for i, status := range statuses {
+				asOf := first.Add(time.Duration(i) * time.Hour)
+
+				wg.Add(2)
+
+				go func(asOf time.Time, status model.Status) {
+					defer wg.Done()
+
+					errs <- m.Save(snapshot(testData(asOf, status)))
+				}(asOf, status)
+
+				go func(asOf time.Time) {
+					defer wg.Done()
+
+					if _, err := m.DataAsOf(asOf); err != nil && !errors.Is(err, model.ErrNoSnapshot) {
+						errs <- err
+					}
+
+					if _, err := m.Snapshots(); err != nil {
+						errs <- err
+					}
+
+					_ = m.Error()
+				}(asOf)
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			snapshots, err := m.Snapshots()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(snapshots) == 0 {
+				t.Error("no snapshots were kept")
+			}
+		})
+	}
+}
+
+// TestUnchangedSaveAdvancesAsOf saves the same content twice: only one
+// snapshot is kept, but the latest data is as of the second check.
+func TestUnchangedSaveAdvancesAsOf(t *testing.T) {
+	nats, _ := newNATS(t)
+
+	backends := map[string]interface {
+		model.Full
+		model.History
+	}{
+		"nats":   nats,
+		"sqlite": newSQLite(t),
+	}
+
+	for name, m := range backends {
+		t.Run(name, func(t *testing.T) {
+			first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+			checked := first.Add(time.Hour)
+
+			for _, asOf := range []time.Time{first, checked} {
+				if err := m.Save(snapshot(testData(asOf, model.StatusNotStarted))); err != nil {
+					t.Fatalf("saving %s: %v", asOf, err)
+				}
+			}
+
+			latest, err := m.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !latest.AsOf.Equal(checked) {
+				t.Errorf("latest as of %s, want %s", latest.AsOf, checked)
+			}
+
+			snapshots, err := m.Snapshots()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(snapshots) != 1 || !snapshots[0].Equal(first) {
+				t.Errorf("snapshots = %v, want only %s", snapshots, first)
+			}
+
+			old, err := m.DataAsOf(first.Add(30 * time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !old.AsOf.Equal(first) {
+				t.Errorf("as of 08:30 found the snapshot of %s, want %s", old.AsOf, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,799 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	model.RegisterHandler("sqlite", func(s string) (func(string) model.Full, bool) {
+		switch {
+		case strings.HasPrefix(s, sqlitePrefix),
+			strings.HasSuffix(s, ".db"),
+			strings.HasSuffix(s, ".sqlite"),
+			strings.HasSuffix(s, ".sqlite3"):
+			return NewSQLiteModel, true
+		}
+
+		return nil, false
+	})
+}
+
+const sqlitePrefix = "sqlite://"
+
+func NewSQLiteModel(conn string) model.Full {
+	return &SQLiteModel{path: strings.TrimPrefix(conn, sqlitePrefix)}
+}
+
+type SQLiteModel struct {
+	path string
+
+	// mu guards the database, which is opened on first use and replaced by
+	// Reset, and the data last read.
+	mu   sync.Mutex
+	db   *sql.DB
+	data model.Data
+}
+
+func (m *SQLiteModel) refresh(ctx context.Context) (model.Data, error) {
+	db, err := m.open(ctx)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	// The latest snapshot is as of when it was last checked, which is later
+	// than when it was taken if the portal has not changed since.
+	working, err := loadSnapshot(ctx, db,
+		`SELECT id, checked_at FROM snapshots ORDER BY as_of DESC LIMIT 1`)
+	if err != nil {
+		return working, err
+	}
+
+	m.mu.Lock()
+	m.data = working
+	m.mu.Unlock()
+
+	return working, nil
+}
+
+// open returns the database, opening it the first time.
+func (m *SQLiteModel) open(ctx context.Context) (*sql.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.db == nil {
+		if err := m.reset(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.db, nil
+}
+
+func (m *SQLiteModel) dataAsOf(ctx context.Context, when time.Time) (model.Data, error) {
+	db, err := m.open(ctx)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	return loadSnapshot(ctx, db,
+		`SELECT id, as_of FROM snapshots WHERE as_of <= ? ORDER BY as_of DESC LIMIT 1`,
+		when.UnixNano())
+}
+
+// loadSnapshot reads the snapshot whose id and time are selected by query.
+func loadSnapshot(ctx context.Context, db *sql.DB, query string, args ...interface{}) (model.Data, error) {
+	var (
+		id      int64
+		asOf    int64
+		working = model.Data{Students: map[int]*model.Student{}}
+	)
+
+	switch err := db.QueryRowContext(ctx, query, args...).Scan(&id, &asOf); err {
+	case nil:
+		working.AsOf = time.Unix(0, asOf)
+	case sql.ErrNoRows:
+		return working, model.ErrNoSnapshot
+	default:
+		return working, err
+	}
+
+	if err := loadStudents(ctx, db, id, &working); err != nil {
+		return working, err
+	}
+
+	if err := loadCourses(ctx, db, id, &working); err != nil {
+		return working, err
+	}
+
+	if err := loadAssignments(ctx, db, id, &working); err != nil {
+		return working, err
+	}
+
+	if err := loadFailures(ctx, db, id, &working); err != nil {
+		return working, err
+	}
+
+	return working, nil
+}
+
+func loadStudents(ctx context.Context, db *sql.DB, snapshot int64, data *model.Data) error {
+	rows, err := db.QueryContext(ctx, `SELECT id, display_name FROM students WHERE snapshot_id = ?`, snapshot)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		student := &model.Student{Courses: map[int]*model.Course{}}
+		if err = rows.Scan(&student.ID, &student.DisplayName); err != nil {
+			return err
+		}
+
+		data.Students[student.ID] = student
+	}
+
+	return rows.Err()
+}
+
+func loadCourses(ctx context.Context, db *sql.DB, snapshot int64, data *model.Data) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, student_id, title, subject, teacher, start_date, end_date, progress, score, stale
+		FROM courses WHERE snapshot_id = ?`, snapshot)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			course = &model.Course{Assignments: map[int]*model.Assignment{}}
+			stale  sql.NullInt64
+		)
+
+		if err = rows.Scan(
+			&course.ID, &course.StudentID, &course.Title, &course.Subject, &course.Teacher,
+			&course.Start, &course.End, &course.Progress, &course.Score, &stale); err != nil {
+			return err
+		}
+
+		if stale.Valid {
+			asOf := time.Unix(0, stale.Int64)
+			course.Stale = &asOf
+		}
+
+		student, ok := data.Students[course.StudentID]
+		if !ok {
+			return fmt.Errorf("course %d references unknown student %d", course.ID, course.StudentID)
+		}
+
+		student.Courses[course.ID] = course
+	}
+
+	return rows.Err()
+}
+
+func loadAssignments(ctx context.Context, db *sql.DB, snapshot int64, data *model.Data) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, student_id, course_id, unit, title, type, progress, due, completed, score, status, extra
+		FROM assignments WHERE snapshot_id = ?`, snapshot)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			a     = &model.Assignment{}
+			extra sql.NullString
+		)
+
+		if err = rows.Scan(
+			&a.ID, &a.StudentID, &a.CourseID, &a.Unit, &a.Title, &a.Type,
+			&a.Progress, &a.Due, &a.Completed, &a.Score, &a.Status, &extra); err != nil {
+			return err
+		}
+
+		if extra.Valid {
+			if err = json.Unmarshal([]byte(extra.String), &a.Extra); err != nil {
+				return fmt.Errorf("assignment %d extra columns: %w", a.ID, err)
+			}
+		}
+
+		student, ok := data.Students[a.StudentID]
+		if !ok {
+			return fmt.Errorf("assignment %d references unknown student %d", a.ID, a.StudentID)
+		}
+
+		course, ok := student.Courses[a.CourseID]
+		if !ok {
+			return fmt.Errorf("assignment %d references unknown course %d", a.ID, a.CourseID)
+		}
+
+		course.Assignments[a.ID] = a
+	}
+
+	return rows.Err()
+}
+
+func loadFailures(ctx context.Context, db *sql.DB, snapshot int64, data *model.Data) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT student_id, student, course_id, course, stage, message
+		FROM failures WHERE snapshot_id = ? ORDER BY rowid`, snapshot)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f model.Failure
+		if err = rows.Scan(&f.StudentID, &f.Student, &f.CourseID, &f.Course, &f.Stage, &f.Message); err != nil {
+			return err
+		}
+
+		data.Failures = append(data.Failures, f)
+	}
+
+	return rows.Err()
+}
+
+func (m *SQLiteModel) Data() (model.Data, error) {
+	return m.DataContext(context.Background())
+}
+
+func (m *SQLiteModel) DataContext(ctx context.Context) (model.Data, error) {
+	data, err := m.refresh(ctx)
+	if err != nil {
+		return model.Data{Errors: []error{err}, AsOf: time.Now()}, err
+	}
+
+	return data, nil
+}
+
+func (m *SQLiteModel) DataAsOf(when time.Time) (model.Data, error) {
+	return m.DataAsOfContext(context.Background(), when)
+}
+
+func (m *SQLiteModel) DataAsOfContext(ctx context.Context, when time.Time) (model.Data, error) {
+	data, err := m.dataAsOf(ctx, when)
+	if err != nil {
+		return model.Data{Errors: []error{err}, AsOf: when}, err
+	}
+
+	return data, nil
+}
+
+func (m *SQLiteModel) Snapshots() ([]time.Time, error) {
+	db, err := m.open(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT as_of FROM snapshots ORDER BY as_of`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []time.Time
+	for rows.Next() {
+		var asOf int64
+		if err = rows.Scan(&asOf); err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, time.Unix(0, asOf))
+	}
+
+	return snapshots, rows.Err()
+}
+
+func (m *SQLiteModel) Save(reader model.Read) error {
+	return m.SaveContext(context.Background(), reader)
+}
+
+func (m *SQLiteModel) SaveContext(ctx context.Context, reader model.Read) error {
+	var (
+		err    error
+		db     *sql.DB
+		s      model.Data
+		digest string
+		tx     *sql.Tx
+	)
+
+	if db, err = m.open(ctx); err != nil {
+		return err
+	}
+
+	if s, err = model.DataContext(ctx, reader); err != nil {
+		return err
+	}
+
+	if digest, err = model.Digest(s); err != nil {
+		return err
+	}
+
+	var (
+		previousID int64
+		previous   string
+	)
+
+	switch err = db.QueryRowContext(ctx,
+		`SELECT id, digest FROM snapshots ORDER BY as_of DESC LIMIT 1`).Scan(&previousID, &previous); err {
+	case nil, sql.ErrNoRows:
+	default:
+		return err
+	}
+
+	// An unchanged snapshot is not kept, but the latest one records when it
+	// was last checked.
+	if previous == digest {
+		_, err = db.ExecContext(ctx,
+			`UPDATE snapshots SET checked_at = ? WHERE id = ? AND checked_at < ?`,
+			s.AsOf.UnixNano(), previousID, s.AsOf.UnixNano())
+
+		return err
+	}
+
+	if tx, err = db.BeginTx(ctx, nil); err != nil {
+		return err
+	}
+
+	if err = writeSQLiteData(ctx, tx, s, digest); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Replace stores d in place of the snapshot taken at d.AsOf, adding it if
+// there is none. Later snapshots are left as they are.
+func (m *SQLiteModel) Replace(d model.Data) error {
+	ctx := context.Background()
+
+	db, err := m.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	digest, err := model.Digest(d)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var checked sql.NullInt64
+	switch err = tx.QueryRowContext(ctx,
+		`SELECT checked_at FROM snapshots WHERE as_of = ?`, d.AsOf.UnixNano()).Scan(&checked); err {
+	case nil, sql.ErrNoRows:
+	default:
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM snapshots WHERE as_of = ?`, d.AsOf.UnixNano()); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = writeSQLiteData(ctx, tx, d, digest); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	// The replaced snapshot was still checked as late as before.
+	if checked.Valid {
+		if _, err = tx.ExecContext(ctx,
+			`UPDATE snapshots SET checked_at = ? WHERE as_of = ? AND checked_at < ?`,
+			checked.Int64, d.AsOf.UnixNano(), checked.Int64); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func writeSQLiteData(ctx context.Context, tx *sql.Tx, s model.Data, digest string) error {
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO snapshots (as_of, checked_at, digest) VALUES (?, ?, ?)`,
+		s.AsOf.UnixNano(), s.AsOf.UnixNano(), digest)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, student := range s.Students {
+		if _, err = tx.ExecContext(ctx,
+			`INSERT INTO students (snapshot_id, id, display_name) VALUES (?, ?, ?)`,
+			snapshot, student.ID, student.DisplayName); err != nil {
+			return err
+		}
+
+		for _, course := range student.Courses {
+			var stale sql.NullInt64
+			if course.Stale != nil {
+				stale = sql.NullInt64{Int64: course.Stale.UnixNano(), Valid: true}
+			}
+
+			if _, err = tx.ExecContext(ctx, `
+				INSERT INTO courses
+					(snapshot_id, id, student_id, title, subject, teacher, start_date, end_date, progress, score, stale)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				snapshot, course.ID, student.ID, course.Title, course.Subject, course.Teacher,
+				course.Start, course.End, course.Progress, course.Score, stale); err != nil {
+				return err
+			}
+
+			for _, a := range course.Assignments {
+				var extra sql.NullString
+				if len(a.Extra) > 0 {
+					b, err := json.Marshal(a.Extra)
+					if err != nil {
+						return err
+					}
+
+					extra = sql.NullString{String: string(b), Valid: true}
+				}
+
+				if _, err = tx.ExecContext(ctx, `
+					INSERT INTO assignments
+						(snapshot_id, id, student_id, course_id, unit, title, type, progress, due, completed, score, status, extra)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+					snapshot, a.ID, student.ID, course.ID, a.Unit, a.Title, a.Type,
+					a.Progress, a.Due, a.Completed, a.Score, a.Status, extra); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, f := range s.Failures {
+		if _, err = tx.ExecContext(ctx, `
+			INSERT INTO failures (snapshot_id, student_id, student, course_id, course, stage, message)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			snapshot, f.StudentID, f.Student, f.CourseID, f.Course, f.Stage, f.Message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadCookies returns the portal cookies saved by SaveCookies, if any.
+func (m *SQLiteModel) LoadCookies() ([]byte, error) {
+	db, err := m.open(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	switch err := db.QueryRow(`SELECT value FROM cookies WHERE id = 1`).Scan(&value); err {
+	case nil, sql.ErrNoRows:
+		return value, nil
+	default:
+		return nil, err
+	}
+}
+
+// SaveCookies keeps the already encrypted portal cookies for the next run.
+func (m *SQLiteModel) SaveCookies(value []byte) error {
+	db, err := m.open(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`INSERT OR REPLACE INTO cookies (id, value) VALUES (1, ?)`, value)
+
+	return err
+}
+
+func (m *SQLiteModel) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.close()
+}
+
+// close closes the database; m.mu must be held.
+func (m *SQLiteModel) close() error {
+	if m.db == nil {
+		return nil
+	}
+
+	err := m.db.Close()
+	m.db = nil
+
+	return err
+}
+
+func (m *SQLiteModel) Reset() error {
+	return m.ResetContext(context.Background())
+}
+
+func (m *SQLiteModel) ResetContext(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reset(ctx)
+}
+
+// reset opens the database, unless it is already open; m.mu must be held.
+func (m *SQLiteModel) reset(ctx context.Context) error {
+	var err error
+
+	m.data = model.Data{AsOf: time.Now()}
+
+	if m.db != nil {
+		if err = m.db.PingContext(ctx); err == nil {
+			return nil
+		}
+
+		if err = m.close(); err != nil {
+			return err
+		}
+	}
+
+	if dir := filepath.Dir(m.path); dir != "" {
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	if m.db, err = sql.Open("sqlite", m.path); err != nil {
+		return err
+	}
+
+	// A single connection keeps the foreign_keys pragma in effect and avoids
+	// SQLITE_BUSY between concurrent writers in this process.
+	m.db.SetMaxOpenConns(1)
+
+	if _, err = m.db.ExecContext(ctx, `PRAGMA foreign_keys = ON`); err != nil {
+		_ = m.close()
+		return err
+	}
+
+	if err = migrateSQLite(ctx, m.db); err != nil {
+		_ = m.close()
+		return err
+	}
+
+	return nil
+}
+
+func (m *SQLiteModel) Error() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.data.Errors) > 0 {
+		return multierror.Append(nil, m.data.Errors...)
+	}
+
+	return nil
+}
+
+type sqliteMigration func(context.Context, *sql.Tx) error
+
+func sqliteExec(stmt string) sqliteMigration {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, stmt)
+		return err
+	}
+}
+
+// sqliteMigrations are applied in order; PRAGMA user_version records how many
+// have already been applied to a database.
+var sqliteMigrations = []sqliteMigration{
+	sqliteExec(`
+	CREATE TABLE students (
+		id           INTEGER PRIMARY KEY,
+		display_name TEXT NOT NULL
+	);
+
+	CREATE TABLE courses (
+		id         INTEGER NOT NULL,
+		student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+		title      TEXT NOT NULL,
+		PRIMARY KEY (student_id, id)
+	);
+
+	CREATE TABLE assignments (
+		id         INTEGER NOT NULL,
+		student_id INTEGER NOT NULL,
+		course_id  INTEGER NOT NULL,
+		unit       INTEGER NOT NULL,
+		title      TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		progress   INTEGER NOT NULL,
+		due        TEXT NOT NULL,
+		completed  TEXT NOT NULL,
+		score      INTEGER NOT NULL,
+		status     TEXT NOT NULL,
+		PRIMARY KEY (student_id, course_id, id),
+		FOREIGN KEY (student_id, course_id) REFERENCES courses (student_id, id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE meta (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`),
+	migrateSQLiteSnapshots,
+	sqliteExec(`
+	ALTER TABLE courses ADD COLUMN subject    TEXT NOT NULL DEFAULT '';
+	ALTER TABLE courses ADD COLUMN teacher    TEXT NOT NULL DEFAULT '';
+	ALTER TABLE courses ADD COLUMN start_date TEXT NOT NULL DEFAULT '';
+	ALTER TABLE courses ADD COLUMN end_date   TEXT NOT NULL DEFAULT '';
+	ALTER TABLE courses ADD COLUMN progress   REAL NOT NULL DEFAULT 0;
+	ALTER TABLE courses ADD COLUMN score      REAL NOT NULL DEFAULT 0;
+	`),
+	sqliteExec(`
+	ALTER TABLE courses ADD COLUMN stale INTEGER;
+
+	CREATE TABLE failures (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+		student_id  INTEGER NOT NULL,
+		student     TEXT NOT NULL,
+		course_id   INTEGER NOT NULL,
+		course      TEXT NOT NULL,
+		stage       TEXT NOT NULL,
+		message     TEXT NOT NULL
+	);
+	`),
+	sqliteExec(`ALTER TABLE assignments ADD COLUMN extra TEXT;`),
+	sqliteExec(`
+	CREATE TABLE cookies (
+		id    INTEGER PRIMARY KEY CHECK (id = 1),
+		value BLOB NOT NULL
+	);
+	`),
+}
+
+// migrateSQLiteSnapshots keys every row by the snapshot it belongs to, so that
+// history is retained, and carries over the single snapshot stored before.
+func migrateSQLiteSnapshots(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+	CREATE TABLE snapshots (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		as_of      INTEGER NOT NULL UNIQUE,
+		checked_at INTEGER NOT NULL,
+		digest     TEXT NOT NULL
+	);
+
+	CREATE TABLE snapshot_students (
+		snapshot_id  INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+		id           INTEGER NOT NULL,
+		display_name TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, id)
+	);
+
+	CREATE TABLE snapshot_courses (
+		snapshot_id INTEGER NOT NULL,
+		id          INTEGER NOT NULL,
+		student_id  INTEGER NOT NULL,
+		title       TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, student_id, id),
+		FOREIGN KEY (snapshot_id, student_id) REFERENCES snapshot_students (snapshot_id, id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE snapshot_assignments (
+		snapshot_id INTEGER NOT NULL,
+		id          INTEGER NOT NULL,
+		student_id  INTEGER NOT NULL,
+		course_id   INTEGER NOT NULL,
+		unit        INTEGER NOT NULL,
+		title       TEXT NOT NULL,
+		type        TEXT NOT NULL,
+		progress    INTEGER NOT NULL,
+		due         TEXT NOT NULL,
+		completed   TEXT NOT NULL,
+		score       INTEGER NOT NULL,
+		status      TEXT NOT NULL,
+		PRIMARY KEY (snapshot_id, student_id, course_id, id),
+		FOREIGN KEY (snapshot_id, student_id, course_id)
+			REFERENCES snapshot_courses (snapshot_id, student_id, id) ON DELETE CASCADE
+	);
+	`); err != nil {
+		return err
+	}
+
+	var asOf string
+	switch err := tx.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'as_of'`).Scan(&asOf); err {
+	case nil:
+		when, err := time.Parse(time.RFC3339Nano, asOf)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `INSERT INTO snapshots (as_of, checked_at, digest) VALUES (?, ?, '')`, when.UnixNano(), when.UnixNano()); err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `
+		INSERT INTO snapshot_students
+			SELECT s.id, t.id, t.display_name FROM snapshots s, students t;
+		INSERT INTO snapshot_courses
+			SELECT s.id, t.id, t.student_id, t.title FROM snapshots s, courses t;
+		INSERT INTO snapshot_assignments
+			SELECT s.id, t.id, t.student_id, t.course_id, t.unit, t.title, t.type,
+				t.progress, t.due, t.completed, t.score, t.status
+			FROM snapshots s, assignments t;
+		`); err != nil {
+			return err
+		}
+	case sql.ErrNoRows:
+	default:
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+	DROP TABLE assignments;
+	DROP TABLE courses;
+	DROP TABLE students;
+	DROP TABLE meta;
+
+	ALTER TABLE snapshot_students RENAME TO students;
+	ALTER TABLE snapshot_courses RENAME TO courses;
+	ALTER TABLE snapshot_assignments RENAME TO assignments;
+	`)
+
+	return err
+}
+
+func migrateSQLite(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = sqliteMigrations[version](ctx, tx); err != nil {
+			_ = tx.Rollback()
+
+			// An abandoned migration is not a broken one.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return fmt.Errorf("sqlite migration %d: %w", version+1, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
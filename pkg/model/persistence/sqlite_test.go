@@ -0,0 +1,206 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "ignitia.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	db := openSQLite(t)
+
+	if err := migrateSQLite(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+
+	// Migrating again is a no-op.
+	if err := migrateSQLite(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateSQLiteAbandoned(t *testing.T) {
+	db := openSQLite(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The caller gives up while the first migration runs.
+	migrations := sqliteMigrations
+	defer func() { sqliteMigrations = migrations }()
+
+	sqliteMigrations = append([]sqliteMigration{func(ctx context.Context, tx *sql.Tx) error {
+		cancel()
+		return migrations[0](ctx, tx)
+	}}, migrations[1:]...)
+
+	err := migrateSQLite(ctx, db)
+	if err != context.Canceled {
+		t.Errorf("err = %v, want the bare context.Canceled", err)
+	}
+}
+
+func newSQLite(t *testing.T) *SQLiteModel {
+	t.Helper()
+
+	m := NewSQLiteModel(filepath.Join(t.TempDir(), "ignitia.db")).(*SQLiteModel)
+	t.Cleanup(func() { _ = m.Close() })
+
+	return m
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	m := newSQLite(t)
+
+	asOf := time.Unix(1662019200, 0)
+	stale := asOf.Add(-24 * time.Hour)
+
+	saved := testData(asOf, model.StatusGraded)
+	course := saved.Students[1].Courses[7]
+	course.Subject, course.Teacher = "Mathematics", "Mr. Rivera"
+	course.Start, course.End = "2022-08-15", "2023-05-26"
+	course.Progress, course.Score = 42.5, 91.25
+	course.Assignments[3].Extra = map[string]interface{}{"kind": "Lab", "points": float64(5)}
+	saved.Students[1].Courses[8] = &model.Course{
+		ID: 8, StudentID: 1, Title: "Science", Stale: &stale, Assignments: map[int]*model.Assignment{},
+	}
+	saved.Failures = []model.Failure{
+		{StudentID: 1, Student: "Anna", CourseID: 8, Course: "Science", Stage: model.StageAssignments, Message: "timeout"},
+		{StudentID: 1, Student: "Anna", CourseID: 8, Course: "Science", Stage: model.StageDetails, Message: "503"},
+	}
+
+	if err := m.SaveContext(context.Background(), snapshot(saved)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.DataContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.AsOf.Equal(asOf) {
+		t.Errorf("as of %s, want %s", got.AsOf, asOf)
+	}
+
+	if !reflect.DeepEqual(got.Students, saved.Students) {
+		t.Errorf("students =\n%#v\nwant\n%#v", got.Students[1], saved.Students[1])
+	}
+
+	if !reflect.DeepEqual(got.Failures, saved.Failures) {
+		t.Errorf("failures = %+v, want %+v", got.Failures, saved.Failures)
+	}
+}
+
+func TestSQLiteSaveKeepsHistory(t *testing.T) {
+	m := newSQLite(t)
+
+	first := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	steps := []struct {
+		asOf   time.Time
+		status model.Status
+	}{
+		{first, model.StatusNotStarted},
+		{first.Add(time.Hour), model.StatusNotStarted},
+		{first.Add(2 * time.Hour), model.StatusCompleted},
+	}
+
+	for _, step := range steps {
+		if err := m.Save(snapshot(testData(step.asOf, step.status))); err != nil {
+			t.Fatalf("saving %s: %v", step.asOf, err)
+		}
+	}
+
+	snapshots, err := m.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The unchanged second snapshot is not kept.
+	if len(snapshots) != 2 || !snapshots[0].Equal(first) || !snapshots[1].Equal(first.Add(2*time.Hour)) {
+		t.Fatalf("snapshots = %v", snapshots)
+	}
+
+	old, err := m.DataAsOf(first.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !old.AsOf.Equal(first) {
+		t.Errorf("as of 09:30 found the snapshot of %s, want %s", old.AsOf, first)
+	}
+
+	if got := old.Students[1].Courses[7].Assignments[3].Status; got != model.StatusNotStarted {
+		t.Errorf("status as of 09:30 = %q, want %q", got, model.StatusNotStarted)
+	}
+
+	if _, err := m.DataAsOf(first.Add(-time.Hour)); !errors.Is(err, model.ErrNoSnapshot) {
+		t.Errorf("data before the first snapshot: err = %v, want ErrNoSnapshot", err)
+	}
+
+	latest, err := m.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := latest.Students[1].Courses[7].Assignments[3].Status; got != model.StatusCompleted {
+		t.Errorf("latest status = %q, want %q", got, model.StatusCompleted)
+	}
+}
+
+func TestSQLiteWithoutSnapshots(t *testing.T) {
+	m := newSQLite(t)
+
+	if _, err := m.Data(); !errors.Is(err, model.ErrNoSnapshot) {
+		t.Errorf("err = %v, want ErrNoSnapshot", err)
+	}
+
+	if snapshots, err := m.Snapshots(); err != nil || len(snapshots) != 0 {
+		t.Errorf("snapshots = %v, %v; want none", snapshots, err)
+	}
+}
+
+func TestSQLiteCookies(t *testing.T) {
+	m := newSQLite(t)
+
+	if got, err := m.LoadCookies(); err != nil || got != nil {
+		t.Fatalf("LoadCookies before saving = %q, %v", got, err)
+	}
+
+	for _, value := range []string{"sealed", "resealed"} {
+		if err := m.SaveCookies([]byte(value)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := m.LoadCookies(); err != nil || string(got) != value {
+			t.Fatalf("LoadCookies = %q, %v; want %q", got, err, value)
+		}
+	}
+}
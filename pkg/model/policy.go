@@ -0,0 +1,342 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy decides when unfinished assignments count as due and overdue, where
+// weeks begin, and how work is graded.
+type Policy struct {
+	// GraceDays is how many days past its due date an assignment may be
+	// before it is overdue.
+	GraceDays int
+	// WeekStart is the first day of the week.
+	WeekStart time.Weekday
+	// DueCutoff is the time of day from which assignments due that day
+	// count as due.
+	DueCutoff time.Duration
+	// DoneProgress is the progress at which an assignment counts as done
+	// before the portal marks it complete; zero ignores progress.
+	DoneProgress int
+	// Types overrides the policy for assignments of some types.
+	Types map[AssignmentType]TypePolicy
+	// Calendar, when set, measures overdue age and the week in school days.
+	Calendar *Calendar
+	// Grading computes course grades and grade point averages.
+	Grading Grading
+}
+
+// TypePolicy overrides parts of a Policy for a type of assignment; fields
+// left nil follow the Policy.
+type TypePolicy struct {
+	GraceDays    *int
+	DueCutoff    *time.Duration
+	DoneProgress *int
+}
+
+// DefaultPolicy returns the policy used unless SetPolicy is called: overdue
+// after a week, weeks starting on Monday, done at full progress, and graded
+// by DefaultGrading.
+func DefaultPolicy() Policy {
+	const (
+		week     = 7
+		finished = 100
+	)
+
+	return Policy{GraceDays: week, WeekStart: time.Monday, DoneProgress: finished, Grading: DefaultGrading()}
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultPolicy()
+)
+
+// SetPolicy replaces the policy used by the assignment, course and student
+// methods.
+func SetPolicy(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+
+	policy = p
+}
+
+// CurrentPolicy returns the policy set by SetPolicy.
+func CurrentPolicy() Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+
+	return policy
+}
+
+// For returns the policy for assignments of type t, with its overrides
+// applied.
+func (p Policy) For(t AssignmentType) Policy {
+	o, ok := p.Types[t]
+	if !ok {
+		return p
+	}
+
+	if o.GraceDays != nil {
+		p.GraceDays = *o.GraceDays
+	}
+
+	if o.DueCutoff != nil {
+		p.DueCutoff = *o.DueCutoff
+	}
+
+	if o.DoneProgress != nil {
+		p.DoneProgress = *o.DoneProgress
+	}
+
+	return p
+}
+
+// IsDueAt reports whether a was due as of now: unfinished, and due before
+// today or due today and past the cutoff. Work due on a day without school is
+// due on the next school day.
+func (p Policy) IsDueAt(a *Assignment, now time.Time) bool {
+	if !p.isOpen(a) {
+		return false
+	}
+
+	p = p.For(a.Type)
+
+	due, today := p.dueDay(a), DayOf(now)
+
+	switch {
+	case due.Before(today):
+		return true
+	case due.Before(today.AddDate(0, 0, 1)):
+		return !now.Before(today.Add(p.DueCutoff))
+	default:
+		return false
+	}
+}
+
+// IsOverdueAt reports whether a was overdue as of now: due, and more than
+// the grace days past its due date, counting only school days.
+func (p Policy) IsOverdueAt(a *Assignment, now time.Time) bool {
+	if !p.IsDueAt(a, now) {
+		return false
+	}
+
+	return p.Calendar.schoolDaysSince(p.dueDay(a), now, p.For(a.Type).GraceDays)
+}
+
+// isOpen reports whether a still needs work: incomplete, and short of the
+// progress at which it counts as done.
+func (p Policy) isOpen(a *Assignment) bool {
+	p = p.For(a.Type)
+
+	if !a.IsIncomplete() {
+		return false
+	}
+
+	return p.DoneProgress == 0 || a.Progress < p.DoneProgress
+}
+
+func (p Policy) dueDay(a *Assignment) time.Time {
+	due := a.DueDate()
+	if due.IsZero() {
+		return due
+	}
+
+	return p.Calendar.NextSchoolDay(due)
+}
+
+// WeekOf returns the start of the week containing t.
+func (p Policy) WeekOf(t time.Time) time.Time {
+	day := DayOf(t)
+
+	return day.AddDate(0, 0, -((int(day.Weekday())-int(p.WeekStart))+daysInWeek)%daysInWeek)
+}
+
+// Week returns the start and end of the school week around t. That is the
+// week containing t, except on a weekday without school, during a break, when
+// it runs from the week of the last school day to the end of the week of the
+// next one.
+func (p Policy) Week(t time.Time) (time.Time, time.Time) {
+	switch t.In(time.Local).Weekday() {
+	case time.Saturday, time.Sunday:
+	default:
+		if !p.Calendar.IsSchoolDay(t) {
+			return p.WeekOf(p.Calendar.PreviousSchoolDay(t)), p.WeekOf(p.Calendar.NextSchoolDay(t)).AddDate(0, 0, daysInWeek)
+		}
+	}
+
+	start := p.WeekOf(t)
+
+	return start, start.AddDate(0, 0, daysInWeek)
+}
+
+// LoadPolicy reads a policy from the JSON file at path, for example
+//
+//	{
+//	  "grace_days": 7,
+//	  "week_start": "monday",
+//	  "due_cutoff": "15:00",
+//	  "done_progress": 100,
+//	  "types": {"test": {"grace_days": 0}},
+//	  "grading": {
+//	    "weights": {"lesson": 20, "quiz": 30, "test": 50},
+//	    "scale": [
+//	      {"letter": "A", "min": 90, "points": 4},
+//	      {"letter": "B", "min": 80, "points": 3},
+//	      {"letter": "F", "min": 0, "points": 0}
+//	    ]
+//	  }
+//	}
+//
+// Settings missing from the file keep their DefaultPolicy values; weights and
+// scale, when present, replace the default ones entirely.
+func LoadPolicy(path string) (Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	p := DefaultPolicy()
+	if err = p.UnmarshalJSON(b); err != nil {
+		return Policy{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+type policyJSON struct {
+	GraceDays    *int                               `json:"grace_days"`
+	WeekStart    *string                            `json:"week_start"`
+	DueCutoff    *string                            `json:"due_cutoff"`
+	DoneProgress *int                               `json:"done_progress"`
+	Types        map[AssignmentType]*typePolicyJSON `json:"types"`
+	Grading      json.RawMessage                    `json:"grading"`
+}
+
+type typePolicyJSON struct {
+	GraceDays    *int    `json:"grace_days"`
+	DueCutoff    *string `json:"due_cutoff"`
+	DoneProgress *int    `json:"done_progress"`
+}
+
+// UnmarshalJSON sets the settings present in b, as described for LoadPolicy.
+func (p *Policy) UnmarshalJSON(b []byte) error {
+	var v policyJSON
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	if v.WeekStart != nil {
+		day, err := parseWeekday(*v.WeekStart)
+		if err != nil {
+			return err
+		}
+
+		p.WeekStart = day
+	}
+
+	if v.GraceDays != nil {
+		p.GraceDays = *v.GraceDays
+	}
+
+	if v.DoneProgress != nil {
+		p.DoneProgress = *v.DoneProgress
+	}
+
+	if v.DueCutoff != nil {
+		cutoff, err := parseClock(*v.DueCutoff)
+		if err != nil {
+			return err
+		}
+
+		p.DueCutoff = cutoff
+	}
+
+	if err := checkPolicy(&p.GraceDays, &p.DoneProgress); err != nil {
+		return err
+	}
+
+	if v.Grading != nil {
+		if err := p.Grading.UnmarshalJSON(v.Grading); err != nil {
+			return fmt.Errorf("grading: %w", err)
+		}
+	}
+
+	for t, o := range v.Types {
+		if err := checkType(t); err != nil {
+			return fmt.Errorf("types: %w", err)
+		}
+
+		if o == nil {
+			continue
+		}
+
+		if err := checkPolicy(o.GraceDays, o.DoneProgress); err != nil {
+			return fmt.Errorf("type %q: %w", t, err)
+		}
+
+		override := TypePolicy{GraceDays: o.GraceDays, DoneProgress: o.DoneProgress}
+
+		if o.DueCutoff != nil {
+			cutoff, err := parseClock(*o.DueCutoff)
+			if err != nil {
+				return fmt.Errorf("type %q: %w", t, err)
+			}
+
+			override.DueCutoff = &cutoff
+		}
+
+		if p.Types == nil {
+			p.Types = map[AssignmentType]TypePolicy{}
+		}
+
+		p.Types[t] = override
+	}
+
+	return nil
+}
+
+// checkPolicy checks the settings that are given.
+func checkPolicy(graceDays, doneProgress *int) error {
+	const finished = 100
+
+	switch {
+	case graceDays != nil && *graceDays < 0:
+		return fmt.Errorf("grace_days must not be negative, got %d", *graceDays)
+	case doneProgress != nil && (*doneProgress < 0 || *doneProgress > finished):
+		return fmt.Errorf("done_progress must be between 0 and %d, got %d", finished, *doneProgress)
+	default:
+		return nil
+	}
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s := strings.ToLower(strings.TrimSpace(s)); s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unrecognized week_start %q", s)
+}
+
+// parseClock parses a time of day, such as "15:00", as the time since
+// midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized due_cutoff %q, expected HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
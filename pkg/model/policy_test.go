@@ -0,0 +1,168 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", s, time.Local)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestPolicyIsDueAt(t *testing.T) {
+	p := DefaultPolicy()
+	p.DueCutoff = 15 * time.Hour
+
+	tests := map[string]struct {
+		a    Assignment
+		now  string
+		want bool
+	}{
+		"due yesterday":         {Assignment{Due: "2022-09-13", Status: StatusNotStarted}, "2022-09-14 08:00", true},
+		"due today, before cut": {Assignment{Due: "2022-09-14", Status: StatusNotStarted}, "2022-09-14 14:59", false},
+		"due today, after cut":  {Assignment{Due: "2022-09-14", Status: StatusInProgress}, "2022-09-14 15:00", true},
+		"due tomorrow":          {Assignment{Due: "2022-09-15", Status: StatusNotStarted}, "2022-09-14 23:00", false},
+		"completed":             {Assignment{Due: "2022-09-13", Status: StatusCompleted}, "2022-09-14 08:00", false},
+		"skipped":               {Assignment{Due: "2022-09-13", Status: StatusSkipped}, "2022-09-14 08:00", false},
+		"fully done":            {Assignment{Due: "2022-09-13", Progress: 100, Status: StatusInProgress}, "2022-09-14 08:00", false},
+		"US date":               {Assignment{Due: "09/13/2022", Status: StatusNotStarted}, "2022-09-14 08:00", true},
+	}
+
+	for name, tc := range tests {
+		if got := p.IsDueAt(&tc.a, day(tc.now)); got != tc.want {
+			t.Errorf("%s: IsDueAt = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestPolicyIsOverdueAt(t *testing.T) {
+	zero := 0
+
+	everyDay := DefaultPolicy()
+
+	weekdays := DefaultPolicy()
+	weekdays.Calendar = NewCalendar(day("2022-09-05"))
+
+	strict := DefaultPolicy()
+	strict.Types = map[AssignmentType]TypePolicy{TypeTest: {GraceDays: &zero}}
+
+	lesson := Assignment{Type: TypeLesson, Due: "2022-09-01", Status: StatusNotStarted}
+	test := Assignment{Type: TypeTest, Due: "2022-09-01", Status: StatusNotStarted}
+
+	tests := map[string]struct {
+		p    Policy
+		a    Assignment
+		now  string
+		want bool
+	}{
+		"within grace":              {everyDay, lesson, "2022-09-08", false},
+		"past grace":                {everyDay, lesson, "2022-09-09", true},
+		"weekends don't count":      {weekdays, lesson, "2022-09-13", false},
+		"past grace in school":      {weekdays, lesson, "2022-09-14", true},
+		"type without grace":        {strict, test, "2022-09-02", true},
+		"other types keep grace":    {strict, lesson, "2022-09-02", false},
+		"not yet due":               {everyDay, Assignment{Due: "2022-09-20", Status: StatusNotStarted}, "2022-09-14", false},
+		"holiday due date moves on": {weekdays, Assignment{Due: "2022-09-03", Status: StatusNotStarted}, "2022-09-14", false},
+	}
+
+	for name, tc := range tests {
+		if got := tc.p.IsOverdueAt(&tc.a, day(tc.now)); got != tc.want {
+			t.Errorf("%s: IsOverdueAt = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestPolicyWeek(t *testing.T) {
+	monday := DefaultPolicy()
+
+	sunday := DefaultPolicy()
+	sunday.WeekStart = time.Sunday
+
+	school := DefaultPolicy()
+	school.Calendar = NewCalendar(day("2022-09-19"))
+
+	sundaySchool := school
+	sundaySchool.WeekStart = time.Sunday
+
+	tests := map[string]struct {
+		p          Policy
+		t          string
+		start, end string
+	}{
+		"midweek":                   {monday, "2022-09-14 10:00", "2022-09-12", "2022-09-19"},
+		"sunday start":              {sunday, "2022-09-14 10:00", "2022-09-11", "2022-09-18"},
+		"saturday":                  {monday, "2022-09-17", "2022-09-12", "2022-09-19"},
+		"sunday":                    {monday, "2022-09-18", "2022-09-12", "2022-09-19"},
+		"sunday start, on saturday": {sunday, "2022-09-17 10:00", "2022-09-11", "2022-09-18"},
+		"sunday start, on sunday":   {sunday, "2022-09-18 10:00", "2022-09-18", "2022-09-25"},
+		"weekend before a break":    {school, "2022-09-17", "2022-09-12", "2022-09-19"},
+		"sunday before a break":     {sundaySchool, "2022-09-18", "2022-09-18", "2022-09-25"},
+		"break":                     {school, "2022-09-19 10:00", "2022-09-12", "2022-09-26"},
+		"break, sunday start":       {sundaySchool, "2022-09-19 10:00", "2022-09-11", "2022-09-25"},
+	}
+
+	for name, tc := range tests {
+		start, end := tc.p.Week(day(tc.t))
+		if !start.Equal(day(tc.start)) || !end.Equal(day(tc.end)) {
+			t.Errorf("%s: week %s - %s, want %s - %s", name, start, end, tc.start, tc.end)
+		}
+	}
+}
+
+func TestPolicyUnmarshalJSON(t *testing.T) {
+	p := DefaultPolicy()
+
+	err := p.UnmarshalJSON([]byte(`{
+		"grace_days": 3,
+		"week_start": "Sun",
+		"due_cutoff": "15:30",
+		"types": {"Test": {"grace_days": 0, "due_cutoff": "08:00"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.GraceDays != 3 || p.WeekStart != time.Sunday || p.DueCutoff != 15*time.Hour+30*time.Minute || p.DoneProgress != 100 {
+		t.Errorf("policy = %+v", p)
+	}
+
+	test := p.For(TypeTest)
+	if test.GraceDays != 0 || test.DueCutoff != 8*time.Hour || test.WeekStart != time.Sunday {
+		t.Errorf("test policy = %+v", test)
+	}
+
+	if lesson := p.For(TypeLesson); lesson.GraceDays != 3 {
+		t.Errorf("lesson grace days = %d, want 3", lesson.GraceDays)
+	}
+
+	errs := map[string]string{
+		`{"grace": 3}`:                            "unknown field",
+		`{"grace_days": -1}`:                      "grace_days must not be negative",
+		`{"done_progress": 101}`:                  "done_progress must be between 0 and 100",
+		`{"week_start": "someday"}`:               `unrecognized week_start "someday"`,
+		`{"due_cutoff": "3pm"}`:                   `unrecognized due_cutoff "3pm"`,
+		`{"types": {"Quiz": {"grace_days": -2}}}`: `type "Quiz": grace_days must not be negative`,
+		`{"grading": {"weights": {"Quiz": -1}}}`:  `grading: weight of "Quiz" must not be negative`,
+		`{"grading": {"scale": []}}`:              "grading: scale must have at least one letter",
+		`{"types": {"tset": {"grace_days": 0}}}`:  `types: unknown assignment type "tset"`,
+		`{"types": {"Homework": null}}`:           `types: unknown assignment type "Homework"`,
+		`{"grading": {"weights": {"tset": 1}}}`:   `grading: weights: unknown assignment type "tset"`,
+	}
+
+	for in, want := range errs {
+		p := DefaultPolicy()
+		if err := p.UnmarshalJSON([]byte(in)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", in, err, want)
+		}
+	}
+}
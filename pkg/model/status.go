@@ -0,0 +1,150 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Status is the state of an assignment as reported by the portal. Any value
+// other than the constants below is unknown; it keeps the portal's text so
+// that it can be shown and reported, but counts as neither complete nor
+// incomplete.
+type Status string
+
+const (
+	StatusNotStarted Status = "Not Started"
+	StatusInProgress Status = "In Progress"
+	StatusCompleted  Status = "Completed"
+	StatusGraded     Status = "Graded"
+	StatusSkipped    Status = "Skipped"
+)
+
+var statuses = []Status{StatusNotStarted, StatusInProgress, StatusCompleted, StatusGraded, StatusSkipped}
+
+// ParseStatus normalizes the portal's text for a status, ignoring case,
+// spacing and punctuation.
+func ParseStatus(s string) Status {
+	for _, status := range statuses {
+		if normal(string(status)) == normal(s) {
+			return status
+		}
+	}
+
+	return Status(strings.TrimSpace(s))
+}
+
+// Known reports whether s is one of the statuses the portal is known to use.
+func (s Status) Known() bool {
+	for _, status := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTerminal reports whether s means the assignment needs no more work.
+func (s Status) IsTerminal() bool {
+	switch s {
+	case StatusCompleted, StatusGraded, StatusSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsOpen reports whether s is a known status meaning the assignment still
+// needs work.
+func (s Status) IsOpen() bool { return s.Known() && !s.IsTerminal() }
+
+// Slug names s in CSS classes and metric labels; every unknown status is
+// "unknown".
+func (s Status) Slug() string { return slug(s.Known(), string(s)) }
+
+func (s *Status) UnmarshalText(b []byte) error {
+	*s = ParseStatus(string(b))
+	return nil
+}
+
+// AssignmentType is the kind of an assignment as reported by the portal. Any
+// value other than the constants below is unknown, and keeps the portal's
+// text.
+type AssignmentType string
+
+const (
+	TypeLesson  AssignmentType = "Lesson"
+	TypeQuiz    AssignmentType = "Quiz"
+	TypeTest    AssignmentType = "Test"
+	TypeExam    AssignmentType = "Exam"
+	TypeProject AssignmentType = "Project"
+)
+
+var assignmentTypes = []AssignmentType{TypeLesson, TypeQuiz, TypeTest, TypeExam, TypeProject}
+
+// ParseAssignmentType normalizes the portal's text for an assignment type,
+// ignoring case, spacing and punctuation.
+func ParseAssignmentType(s string) AssignmentType {
+	for _, t := range assignmentTypes {
+		if normal(string(t)) == normal(s) {
+			return t
+		}
+	}
+
+	return AssignmentType(strings.TrimSpace(s))
+}
+
+// Known reports whether t is one of the types the portal is known to use.
+func (t AssignmentType) Known() bool {
+	for _, known := range assignmentTypes {
+		if t == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkType rejects a type the portal is not known to use, such as a
+// misspelled key in a policy.
+func checkType(t AssignmentType) error {
+	if t.Known() {
+		return nil
+	}
+
+	names := make([]string, 0, len(assignmentTypes))
+	for _, known := range assignmentTypes {
+		names = append(names, string(known))
+	}
+
+	return fmt.Errorf("unknown assignment type %q, want one of %s", t, strings.Join(names, ", "))
+}
+
+// Slug names t in CSS classes and metric labels; every unknown type is
+// "unknown".
+func (t AssignmentType) Slug() string { return slug(t.Known(), string(t)) }
+
+func (t *AssignmentType) UnmarshalText(b []byte) error {
+	*t = ParseAssignmentType(string(b))
+	return nil
+}
+
+// normal reduces s to its lower case letters.
+func normal(s string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, s)
+}
+
+func slug(known bool, s string) string {
+	if !known {
+		return "unknown"
+	}
+
+	return strings.ReplaceAll(strings.ToLower(s), " ", "-")
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := map[string]Status{
+		"Not Started":  StatusNotStarted,
+		"not started":  StatusNotStarted,
+		"NotStarted":   StatusNotStarted,
+		"not_started":  StatusNotStarted,
+		" In-Progress": StatusInProgress,
+		"COMPLETED":    StatusCompleted,
+		"graded.":      StatusGraded,
+		"Skipped":      StatusSkipped,
+		" Excused ":    "Excused",
+		"":             "",
+	}
+
+	for s, want := range tests {
+		if got := ParseStatus(s); got != want {
+			t.Errorf("ParseStatus(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		status                Status
+		known, open, terminal bool
+		slug                  string
+	}{
+		{StatusNotStarted, true, true, false, "not-started"},
+		{StatusInProgress, true, true, false, "in-progress"},
+		{StatusCompleted, true, false, true, "completed"},
+		{StatusGraded, true, false, true, "graded"},
+		{StatusSkipped, true, false, true, "skipped"},
+		{"Excused", false, false, false, "unknown"},
+		{"", false, false, false, "unknown"},
+	}
+
+	for _, tc := range tests {
+		if got := tc.status.Known(); got != tc.known {
+			t.Errorf("%q: Known = %t, want %t", tc.status, got, tc.known)
+		}
+
+		if got := tc.status.IsOpen(); got != tc.open {
+			t.Errorf("%q: IsOpen = %t, want %t", tc.status, got, tc.open)
+		}
+
+		if got := tc.status.IsTerminal(); got != tc.terminal {
+			t.Errorf("%q: IsTerminal = %t, want %t", tc.status, got, tc.terminal)
+		}
+
+		if got := tc.status.Slug(); got != tc.slug {
+			t.Errorf("%q: Slug = %q, want %q", tc.status, got, tc.slug)
+		}
+	}
+}
+
+func TestStatusUnknownRoundTrip(t *testing.T) {
+	var a Assignment
+	if err := json.Unmarshal([]byte(`{"status":"  Excused "}`), &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Status != "Excused" {
+		t.Fatalf("status = %q, want the portal's text", a.Status)
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var again Assignment
+	if err = json.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+
+	if again.Status != a.Status || again.Status.Known() {
+		t.Errorf("status after a round trip = %q", again.Status)
+	}
+}
+
+func TestIsCompletedThisWeek(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	lastYear := time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
+
+	tests := map[string]struct {
+		a    Assignment
+		want bool
+	}{
+		"completed today":          {Assignment{Status: StatusCompleted, Completed: today}, true},
+		"graded today":             {Assignment{Status: StatusGraded, Completed: today}, true},
+		"skipped today":            {Assignment{Status: StatusSkipped, Completed: today}, true},
+		"completed last year":      {Assignment{Status: StatusCompleted, Completed: lastYear}, false},
+		"completed without a date": {Assignment{Status: StatusCompleted}, false},
+		"in progress":              {Assignment{Status: StatusInProgress, Completed: today}, false},
+		"unknown status":           {Assignment{Status: "Excused", Completed: today}, false},
+	}
+
+	for name, tc := range tests {
+		if got := tc.a.IsCompletedThisWeek(); got != tc.want {
+			t.Errorf("%s: IsCompletedThisWeek = %t, want %t", name, got, tc.want)
+		}
+	}
+}
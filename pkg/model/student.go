@@ -44,3 +44,11 @@ func (s *Student) OverdueAssignments() int {
 	}
 	return overdue
 }
+
+func (s *Student) CompletedThisWeek() int {
+	var completed int
+	for _, c := range s.Courses {
+		completed += c.CompletedThisWeek()
+	}
+	return completed
+}
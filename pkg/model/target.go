@@ -0,0 +1,223 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxScore is the best score an assignment can earn.
+const maxScore = 100
+
+// Target is what it takes to reach a course grade.
+type Target struct {
+	// Grade is the score to reach, out of 100.
+	Grade float64 `json:"grade"`
+	// Letter is the letter grade for Grade, when it names one.
+	Letter string `json:"letter,omitempty"`
+	// Current is the course's score so far, if any work is graded.
+	Current *float64 `json:"current,omitempty"`
+	// Remaining is how many incomplete assignments count towards the grade.
+	Remaining int `json:"remaining"`
+	// Score is the average score needed on all of the remaining assignments;
+	// zero when the grade is reached whatever they score.
+	Score float64 `json:"score"`
+	// Reachable reports whether Score is possible.
+	Reachable bool `json:"reachable"`
+	// Types breaks the target down by the types of the remaining
+	// assignments.
+	Types []TypeTarget `json:"types,omitempty"`
+}
+
+// TypeTarget is what the remaining assignments of one type need to score,
+// assuming the rest of the remaining work scores the student's average for
+// its type so far, or, for types without grades yet, the average needed on
+// all of the remaining work; so it differs from Target.Score when those
+// averages do.
+type TypeTarget struct {
+	Type      AssignmentType `json:"type"`
+	Remaining int            `json:"remaining"`
+	Score     float64        `json:"score"`
+	Reachable bool           `json:"reachable"`
+}
+
+func (t Target) String() string { return needed(t.Score, t.Reachable) }
+
+func (t TypeTarget) String() string { return needed(t.Score, t.Reachable) }
+
+func needed(score float64, reachable bool) string {
+	switch {
+	case !reachable:
+		return "out of reach"
+	case score == 0:
+		return "secured"
+	default:
+		return fmt.Sprintf("%.0f%%", score)
+	}
+}
+
+// Target returns what it takes to reach grade in the course, under the
+// current policy.
+func (c *Course) Target(grade float64) Target { return CurrentPolicy().Grading.Target(c, grade) }
+
+// Targets returns what it takes to reach each passing letter grade in the
+// course, from highest to lowest, or nil if no incomplete work counts towards
+// its grade.
+func (c *Course) Targets() []Target {
+	g := CurrentPolicy().Grading
+
+	var targets []Target
+	for _, letter := range g.Scale {
+		if letter.Points <= 0 {
+			continue
+		}
+
+		target := g.Target(c, letter.Min)
+		if target.Remaining == 0 {
+			return nil
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// tally sums the graded and counts the incomplete assignments of a type.
+type tally struct {
+	total             float64
+	graded, remaining int
+}
+
+// Target returns the average score the incomplete assignments in c need to
+// bring its grade to grade.
+func (g Grading) Target(c *Course, grade float64) Target {
+	tallies := map[AssignmentType]*tally{}
+
+	for _, a := range c.all().Assignments {
+		if g.Weights[a.Type] <= 0 {
+			continue
+		}
+
+		t, ok := tallies[a.Type]
+		if !ok {
+			t = &tally{}
+			tallies[a.Type] = t
+		}
+
+		switch {
+		case a.IsGraded():
+			t.total += float64(a.Score)
+			t.graded++
+		case a.IsIncomplete():
+			t.remaining++
+		}
+	}
+
+	target := Target{Grade: grade}
+
+	if letter := g.Letter(grade); letter.Min == grade {
+		target.Letter = letter.Letter
+	}
+
+	if current := g.Grade(c); current != nil {
+		target.Current = &current.Score
+	}
+
+	target.Score, target.Reachable = g.solve(tallies, grade, func(score float64) func(AssignmentType) float64 {
+		return func(AssignmentType) float64 { return score }
+	})
+
+	for t, tally := range tallies {
+		if tally.remaining == 0 {
+			continue
+		}
+
+		target.Remaining += tally.remaining
+
+		typ := t
+		score, reachable := g.solve(tallies, grade, func(score float64) func(AssignmentType) float64 {
+			return func(u AssignmentType) float64 {
+				switch other := tallies[u]; {
+				case u == typ:
+					return score
+				case other.graded > 0:
+					return other.total / float64(other.graded)
+				default:
+					return math.Min(target.Score, maxScore)
+				}
+			}
+		})
+
+		target.Types = append(target.Types, TypeTarget{Type: t, Remaining: tally.remaining, Score: score, Reachable: reachable})
+	}
+
+	sort.Slice(target.Types, func(x, y int) bool { return target.Types[x].Type < target.Types[y].Type })
+
+	return target
+}
+
+// solve finds the score, between zero and maxScore, that the remaining work
+// scored by scores needs to bring the grade to grade. The grade rises in a
+// straight line with the score, so two guesses are enough.
+func (g Grading) solve(tallies map[AssignmentType]*tally, grade float64, scores func(float64) func(AssignmentType) float64) (float64, bool) {
+	low, high := g.project(tallies, scores(0)), g.project(tallies, scores(maxScore))
+
+	switch {
+	case low >= grade:
+		return 0, true
+	case high <= low:
+		return 0, false
+	}
+
+	// Round away the noise of the arithmetic, which could otherwise put a
+	// perfect score out of reach.
+	const hundredths = 100
+
+	score := math.Round(hundredths*maxScore*(grade-low)/(high-low)) / hundredths
+
+	return score, score <= maxScore
+}
+
+// project returns the grade the course would have if its remaining work
+// scored as given.
+func (g Grading) project(tallies map[AssignmentType]*tally, score func(AssignmentType) float64) float64 {
+	var total, weight float64
+
+	for t, tally := range tallies {
+		n := tally.graded + tally.remaining
+		if n == 0 {
+			continue
+		}
+
+		total += g.Weights[t] * (tally.total + score(t)*float64(tally.remaining)) / float64(n)
+		weight += g.Weights[t]
+	}
+
+	if weight == 0 {
+		return 0
+	}
+
+	return total / weight
+}
+
+// ParseTarget parses a target grade, either a letter on the scale or a score
+// out of 100.
+func (g Grading) ParseTarget(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+
+	for _, letter := range g.Scale {
+		if strings.EqualFold(letter.Letter, s) {
+			return letter.Min, nil
+		}
+	}
+
+	grade, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || grade < 0 || grade > maxScore {
+		return 0, fmt.Errorf("unrecognized target grade %q, expected a letter or a score out of %d", s, maxScore)
+	}
+
+	return grade, nil
+}
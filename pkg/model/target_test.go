@@ -0,0 +1,111 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGradingTarget(t *testing.T) {
+	c := gradedCourse(
+		Assignment{Type: TypeLesson, Score: 90, Status: StatusGraded},
+		Assignment{Type: TypeLesson, Status: StatusInProgress},
+		Assignment{Type: TypeTest, Status: StatusNotStarted},
+		Assignment{Type: TypeQuiz, Score: 50, Status: StatusSkipped},
+	)
+
+	current := 90.0
+
+	tests := map[string]struct {
+		grade float64
+		want  Target
+	}{
+		// Lessons keep their average of 90 while the test makes up the
+		// rest, and the test, without a grade yet, scores the 70 needed
+		// overall while the lesson does.
+		"by type": {75, Target{
+			Grade: 75, Current: &current, Remaining: 2, Score: 70, Reachable: true,
+			Types: []TypeTarget{
+				{Type: TypeLesson, Remaining: 1, Score: 70, Reachable: true},
+				{Type: TypeTest, Remaining: 1, Score: 60, Reachable: true},
+			},
+		}},
+		"letter": {80, Target{
+			Grade: 80, Letter: "B", Current: &current, Remaining: 2, Score: 76.67, Reachable: true,
+			Types: []TypeTarget{
+				{Type: TypeLesson, Remaining: 1, Score: 76.66, Reachable: true},
+				{Type: TypeTest, Remaining: 1, Score: 70, Reachable: true},
+			},
+		}},
+		"secured": {20, Target{
+			Grade: 20, Current: &current, Remaining: 2, Score: 0, Reachable: true,
+			Types: []TypeTarget{
+				{Type: TypeLesson, Remaining: 1, Score: 0, Reachable: true},
+				{Type: TypeTest, Remaining: 1, Score: 0, Reachable: true},
+			},
+		}},
+		"out of reach": {99, Target{
+			Grade: 99, Current: &current, Remaining: 2, Score: 102, Reachable: false,
+			Types: []TypeTarget{
+				{Type: TypeLesson, Remaining: 1, Score: 106, Reachable: false},
+				{Type: TypeTest, Remaining: 1, Score: 108, Reachable: false},
+			},
+		}},
+	}
+
+	for name, tc := range tests {
+		if got := DefaultGrading().Target(c, tc.grade); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: target %#v, want %#v", name, got, tc.want)
+		}
+	}
+}
+
+func TestCourseTargets(t *testing.T) {
+	c := gradedCourse(
+		Assignment{Type: TypeLesson, Score: 90, Status: StatusGraded},
+		Assignment{Type: TypeLesson, Status: StatusNotStarted},
+	)
+
+	var letters []string
+	for _, target := range c.Targets() {
+		letters = append(letters, target.Letter)
+	}
+
+	if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(letters, want) {
+		t.Errorf("letters %v, want %v", letters, want)
+	}
+
+	done := gradedCourse(Assignment{Type: TypeLesson, Score: 90, Status: StatusGraded})
+	if targets := done.Targets(); targets != nil {
+		t.Errorf("targets without remaining work: %+v", targets)
+	}
+}
+
+func TestGradingParseTarget(t *testing.T) {
+	g := DefaultGrading()
+
+	for in, want := range map[string]float64{"b": 80, " A ": 90, "85": 85, "72.5%": 72.5, "0": 0} {
+		if got, err := g.ParseTarget(in); err != nil || got != want {
+			t.Errorf("ParseTarget(%q) = %g, %v, want %g", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "Z", "101", "-1", "high"} {
+		if _, err := g.ParseTarget(in); err == nil {
+			t.Errorf("ParseTarget(%q) succeeded", in)
+		}
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	tests := map[string]Target{
+		"out of reach": {Score: 102},
+		"secured":      {Reachable: true},
+		"77%":          {Score: 76.67, Reachable: true},
+	}
+
+	for want, target := range tests {
+		if got := target.String(); got != want {
+			t.Errorf("%#v: %q, want %q", target, got, want)
+		}
+	}
+}
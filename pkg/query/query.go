@@ -0,0 +1,470 @@
+// Package query parses the filter expressions shared by the command line and
+// the web report, such as
+//
+//	student:"Anna" course~math status!=Completed due<=+3d type:test score<70
+//
+// An expression is a list of terms separated by spaces, all of which an
+// assignment must match. A term is a field, an operator and a value; values
+// with spaces go in double quotes. A term starting with - matches what it
+// would not, and a term without an operator matches assignment titles
+// containing it.
+//
+// Text fields are student, course, subject, teacher and title. For them
+// field:value matches all of the words of value, ignoring case; = and !=
+// compare the whole text, and ~ and !~ look for value anywhere in it. A
+// student may also be given by ID.
+//
+// Number fields are unit, progress and score, and date fields are due and
+// completed. They compare with :, =, !=, <, <=, > and >=. Dates are written
+// as 2006-01-02, today, tomorrow, yesterday, or a number of days or weeks
+// from today, such as +3d or -2w; assignments without a date never match,
+// and neither do ungraded assignments for score.
+//
+// The type and status fields take the portal's names for them, or unknown.
+// The is field takes due, overdue, incomplete, complete, graded, current,
+// future or past.
+package query
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// Predicate decides whether an assignment, in its course and student, is
+// wanted.
+type Predicate func(*model.Student, *model.Course, *model.Assignment) bool
+
+// Query is a parsed expression.
+type Query struct {
+	source string
+	terms  []Predicate
+}
+
+// Parse parses expr, resolving dates relative to today. An empty expression
+// matches every assignment.
+func Parse(expr string) (*Query, error) { return ParseAt(expr, time.Now()) }
+
+// ParseAt parses expr, resolving dates relative to the day of now.
+func ParseAt(expr string, now time.Time) (*Query, error) {
+	terms, err := split(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{source: expr}
+
+	for _, t := range terms {
+		match, err := t.parse(now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.raw, err)
+		}
+
+		q.terms = append(q.terms, match)
+	}
+
+	return q, nil
+}
+
+// Match reports whether the assignment matches every term of the query.
+func (q *Query) Match(s *model.Student, c *model.Course, a *model.Assignment) bool {
+	for _, match := range q.terms {
+		if !match(s, c, a) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (q *Query) String() string { return q.source }
+
+// term is one term of an expression, with its quotes removed.
+type term struct {
+	raw  string
+	text string
+	// quoted is where in text the first quoted part starts, or -1.
+	quoted int
+}
+
+// split breaks expr into terms at the spaces outside double quotes.
+func split(expr string) ([]term, error) {
+	var (
+		terms   []term
+		current *term
+		inQuote bool
+		escaped bool
+		start   int
+	)
+
+	for i, r := range expr {
+		switch {
+		case escaped:
+			current.text += string(r)
+			escaped = false
+
+			continue
+		case inQuote && r == '\\':
+			escaped = true
+
+			continue
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if current != nil {
+				current.raw = expr[start:i]
+				terms = append(terms, *current)
+				current = nil
+			}
+
+			continue
+		}
+
+		if current == nil {
+			current = &term{quoted: -1}
+			start = i
+		}
+
+		if r == '"' {
+			if !inQuote && current.quoted < 0 {
+				current.quoted = len(current.text)
+			}
+
+			inQuote = !inQuote
+
+			continue
+		}
+
+		current.text += string(r)
+	}
+
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in %q", expr)
+	}
+
+	if current != nil {
+		current.raw = expr[start:]
+		terms = append(terms, *current)
+	}
+
+	return terms, nil
+}
+
+// operators, longest first so that <= is not read as <.
+var operators = []string{"!=", "!~", "<=", ">=", ":", "=", "~", "<", ">"}
+
+func (t term) parse(now time.Time) (Predicate, error) {
+	text, negate := t.text, false
+	if strings.HasPrefix(text, "-") && t.quoted != 0 {
+		text, negate = text[1:], true
+	}
+
+	offset := len(t.text) - len(text)
+
+	match, err := parseTerm(text, t.quoted-offset, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		return func(s *model.Student, c *model.Course, a *model.Assignment) bool { return !match(s, c, a) }, nil
+	}
+
+	return match, nil
+}
+
+func parseTerm(text string, quoted int, now time.Time) (Predicate, error) {
+	field, op, value, ok := cut(text, quoted)
+	if !ok {
+		return fields["title"]("~", text, now)
+	}
+
+	build, ok := fields[strings.ToLower(field)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+
+	return build(op, value, now)
+}
+
+// cut splits text into a field, an operator and a value, if it starts with a
+// field name followed by an operator outside any quotes.
+func cut(text string, quoted int) (field, op, value string, ok bool) {
+	end := strings.IndexFunc(text, func(r rune) bool { return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '_' })
+	if end <= 0 {
+		return "", "", "", false
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(text[end:], op) && (quoted < 0 || end+len(op) <= quoted) {
+			return text[:end], op, text[end+len(op):], true
+		}
+	}
+
+	return "", "", "", false
+}
+
+// builder makes the predicate for a field compared with value by op.
+type builder func(op, value string, now time.Time) (Predicate, error)
+
+var fields = map[string]builder{
+	"student": studentField,
+	"course": textField(func(_ *model.Student, c *model.Course, _ *model.Assignment) string {
+		return html.UnescapeString(c.Title)
+	}),
+	"subject":   textField(func(_ *model.Student, c *model.Course, _ *model.Assignment) string { return c.Subject }),
+	"teacher":   textField(func(_ *model.Student, c *model.Course, _ *model.Assignment) string { return c.Teacher }),
+	"title":     textField(func(_ *model.Student, _ *model.Course, a *model.Assignment) string { return a.Title }),
+	"unit":      numberField(func(a *model.Assignment) int { return a.Unit }),
+	"progress":  numberField(func(a *model.Assignment) int { return a.Progress }),
+	"score":     graded(numberField(func(a *model.Assignment) int { return a.Score })),
+	"due":       dateField((*model.Assignment).DueDate),
+	"completed": dateField((*model.Assignment).CompleteDate),
+	"type":      typeField,
+	"status":    statusField,
+	"is":        isField,
+}
+
+func textField(get func(*model.Student, *model.Course, *model.Assignment) string) builder {
+	return func(op, value string, _ time.Time) (Predicate, error) {
+		want := strings.ToLower(value)
+
+		var match func(string) bool
+
+		switch op {
+		case ":":
+			words := strings.Fields(want)
+			match = func(s string) bool { return hasWords(strings.Fields(s), words) }
+		case "=", "!=":
+			match = func(s string) bool { return (strings.ToLower(s) == want) == (op == "=") }
+		case "~", "!~":
+			match = func(s string) bool { return strings.Contains(strings.ToLower(s), want) == (op == "~") }
+		default:
+			return nil, fmt.Errorf("text cannot be compared with %s", op)
+		}
+
+		return func(s *model.Student, c *model.Course, a *model.Assignment) bool { return match(get(s, c, a)) }, nil
+	}
+}
+
+// hasWords reports whether every one of words, in lower case, is among have.
+func hasWords(have, words []string) bool {
+	for _, word := range words {
+		var found bool
+
+		for _, h := range have {
+			if strings.ToLower(h) == word {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func studentField(op, value string, now time.Time) (Predicate, error) {
+	byName, err := textField(func(s *model.Student, _ *model.Course, _ *model.Assignment) string {
+		return html.UnescapeString(s.DisplayName)
+	})(op, value, now)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return byName, nil
+	}
+
+	switch op {
+	case ":", "=":
+		return func(s *model.Student, c *model.Course, a *model.Assignment) bool {
+			return s.ID == id || byName(s, c, a)
+		}, nil
+	case "!=":
+		return func(s *model.Student, c *model.Course, a *model.Assignment) bool {
+			return s.ID != id && byName(s, c, a)
+		}, nil
+	default:
+		return byName, nil
+	}
+}
+
+func numberField(get func(*model.Assignment) int) builder {
+	return func(op, value string, _ time.Time) (Predicate, error) {
+		want, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+
+		compare, err := comparison(op)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(_ *model.Student, _ *model.Course, a *model.Assignment) bool {
+			return compare(get(a) - want)
+		}, nil
+	}
+}
+
+// graded narrows the predicates build makes to graded assignments.
+func graded(build builder) builder {
+	return func(op, value string, now time.Time) (Predicate, error) {
+		match, err := build(op, value, now)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(s *model.Student, c *model.Course, a *model.Assignment) bool {
+			return a.IsGraded() && match(s, c, a)
+		}, nil
+	}
+}
+
+func dateField(get func(*model.Assignment) time.Time) builder {
+	return func(op, value string, now time.Time) (Predicate, error) {
+		want, err := parseDay(value, now)
+		if err != nil {
+			return nil, err
+		}
+
+		compare, err := comparison(op)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(_ *model.Student, _ *model.Course, a *model.Assignment) bool {
+			day := get(a)
+			if day.IsZero() {
+				return false
+			}
+
+			switch day = model.DayOf(day); {
+			case day.Before(want):
+				return compare(-1)
+			case day.After(want):
+				return compare(1)
+			default:
+				return compare(0)
+			}
+		}, nil
+	}
+}
+
+// comparison returns whether a difference satisfies op.
+func comparison(op string) (func(int) bool, error) {
+	switch op {
+	case ":", "=":
+		return func(d int) bool { return d == 0 }, nil
+	case "!=":
+		return func(d int) bool { return d != 0 }, nil
+	case "<":
+		return func(d int) bool { return d < 0 }, nil
+	case "<=":
+		return func(d int) bool { return d <= 0 }, nil
+	case ">":
+		return func(d int) bool { return d > 0 }, nil
+	case ">=":
+		return func(d int) bool { return d >= 0 }, nil
+	default:
+		return nil, fmt.Errorf("numbers and dates cannot be compared with %s", op)
+	}
+}
+
+// parseDay parses a date, or a day relative to the day of now.
+func parseDay(value string, now time.Time) (time.Time, error) {
+	const daysInWeek = 7
+
+	today := model.DayOf(now)
+
+	switch strings.ToLower(value) {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "01/02/2006"} {
+		if day, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return day, nil
+		}
+	}
+
+	if len(value) > 2 && (value[0] == '+' || value[0] == '-') {
+		n, err := strconv.Atoi(value[1 : len(value)-1])
+
+		switch unit := strings.ToLower(value[len(value)-1:]); {
+		case err != nil:
+		case unit == "d":
+			return today.AddDate(0, 0, sign(value)*n), nil
+		case unit == "w":
+			return today.AddDate(0, 0, sign(value)*n*daysInWeek), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q, expected YYYY-MM-DD, today, or days or weeks from today like +3d", value)
+}
+
+func sign(value string) int {
+	if value[0] == '-' {
+		return -1
+	}
+
+	return 1
+}
+
+func typeField(op, value string, _ time.Time) (Predicate, error) {
+	is := func(t model.AssignmentType) bool {
+		return t == model.ParseAssignmentType(value) || strings.EqualFold(t.Slug(), value)
+	}
+
+	return equality(op, func(a *model.Assignment) bool { return is(a.Type) })
+}
+
+func statusField(op, value string, _ time.Time) (Predicate, error) {
+	is := func(s model.Status) bool {
+		return s == model.ParseStatus(value) || strings.EqualFold(s.Slug(), value)
+	}
+
+	return equality(op, func(a *model.Assignment) bool { return is(a.Status) })
+}
+
+var flags = map[string]func(*model.Assignment) bool{
+	"due":        (*model.Assignment).IsDue,
+	"overdue":    (*model.Assignment).IsOverdue,
+	"incomplete": (*model.Assignment).IsIncomplete,
+	"complete":   func(a *model.Assignment) bool { return a.Status.IsTerminal() },
+	"graded":     (*model.Assignment).IsGraded,
+	"current":    (*model.Assignment).IsCurrent,
+	"future":     (*model.Assignment).IsFuture,
+	"past":       (*model.Assignment).IsPast,
+}
+
+func isField(op, value string, _ time.Time) (Predicate, error) {
+	flag, ok := flags[strings.ToLower(value)]
+	if !ok {
+		return nil, fmt.Errorf("unknown flag %q", value)
+	}
+
+	return equality(op, flag)
+}
+
+func equality(op string, is func(*model.Assignment) bool) (Predicate, error) {
+	switch op {
+	case ":", "=":
+		return func(_ *model.Student, _ *model.Course, a *model.Assignment) bool { return is(a) }, nil
+	case "!=":
+		return func(_ *model.Student, _ *model.Course, a *model.Assignment) bool { return !is(a) }, nil
+	default:
+		return nil, fmt.Errorf("only :, = and != apply to this field, not %s", op)
+	}
+}
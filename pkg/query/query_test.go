@@ -0,0 +1,133 @@
+package query
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+func testData() model.Data {
+	anna := &model.Student{ID: 1, DisplayName: "Anna Example", Courses: map[int]*model.Course{
+		7: {ID: 7, StudentID: 1, Title: "Algebra I", Subject: "Math", Teacher: "Mr. Rivera", Assignments: map[int]*model.Assignment{
+			1: {ID: 1, Unit: 1, Title: "Variables", Type: model.TypeLesson, Progress: 100, Due: "2022-09-01", Completed: "2022-08-31", Score: 95, Status: model.StatusGraded},
+			2: {ID: 2, Unit: 2, Title: "Linear Equations", Type: model.TypeQuiz, Progress: 50, Due: "2022-09-13", Status: model.StatusInProgress},
+			3: {ID: 3, Unit: 3, Title: "Midterm", Type: model.TypeTest, Due: "2022-09-20", Status: model.StatusNotStarted},
+		}},
+	}}
+
+	ben := &model.Student{ID: 2, DisplayName: "Ben", Courses: map[int]*model.Course{
+		8: {ID: 8, StudentID: 2, Title: "Biology", Subject: "Science", Teacher: "Ms. Chen", Assignments: map[int]*model.Assignment{
+			4: {ID: 4, Unit: 1, Title: "Cells", Type: model.TypeLesson, Progress: 100, Due: "2022-09-12", Completed: "2022-09-12", Score: 65, Status: model.StatusCompleted},
+			5: {ID: 5, Unit: 2, Title: "Genetics", Type: model.TypeTest, Status: model.StatusNotStarted},
+		}},
+	}}
+
+	return model.Data{Students: map[int]*model.Student{1: anna, 2: ben}}
+}
+
+// matching returns the titles of the assignments in data that q matches, in
+// order.
+func matching(data model.Data, q *Query) []string {
+	titles := []string{}
+
+	for _, s := range data.Students {
+		for _, c := range s.Courses {
+			for _, a := range c.Assignments {
+				if q.Match(s, c, a) {
+					titles = append(titles, a.Title)
+				}
+			}
+		}
+	}
+
+	sort.Strings(titles)
+
+	return titles
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2022, 9, 14, 10, 0, 0, 0, time.Local)
+	all := []string{"Cells", "Genetics", "Linear Equations", "Midterm", "Variables"}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", all},
+		{"student:anna", []string{"Linear Equations", "Midterm", "Variables"}},
+		{`student:"example anna"`, []string{"Linear Equations", "Midterm", "Variables"}},
+		{"student=2", []string{"Cells", "Genetics"}},
+		{"student!=1", []string{"Cells", "Genetics"}},
+		{"student=ben", []string{"Cells", "Genetics"}},
+		{"course~ALG", []string{"Linear Equations", "Midterm", "Variables"}},
+		{`course="algebra i"`, []string{"Linear Equations", "Midterm", "Variables"}},
+		{"subject!~sci", []string{"Linear Equations", "Midterm", "Variables"}},
+		{`teacher="ms. chen"`, []string{"Cells", "Genetics"}},
+		{"equations", []string{"Linear Equations"}},
+		{"-equations", []string{"Cells", "Genetics", "Midterm", "Variables"}},
+		{`title:"equations linear"`, []string{"Linear Equations"}},
+		{`"-equations"`, []string{}},
+		{"score<70", []string{"Cells"}},
+		{"-score<70", []string{"Genetics", "Linear Equations", "Midterm", "Variables"}},
+		{"score>=90%", []string{"Variables"}},
+		{"progress:100", []string{"Cells", "Variables"}},
+		{"unit>=2 type!=quiz", []string{"Genetics", "Midterm"}},
+		{"due<=today", []string{"Cells", "Linear Equations", "Variables"}},
+		{"due:yesterday", []string{"Linear Equations"}},
+		{"due>+3d", []string{"Midterm"}},
+		{"due>=-1w due<tomorrow", []string{"Cells", "Linear Equations"}},
+		{"due=09/12/2022", []string{"Cells"}},
+		{"completed<2022-09-01", []string{"Variables"}},
+		{"type:test", []string{"Genetics", "Midterm"}},
+		{"status=graded", []string{"Variables"}},
+		{`status:"not started"`, []string{"Genetics", "Midterm"}},
+		{"status=unknown", []string{}},
+		{"is:graded", []string{"Cells", "Variables"}},
+		{"is!=incomplete", []string{"Cells", "Variables"}},
+		{`course:"algebra i" -is:graded`, []string{"Linear Equations", "Midterm"}},
+	}
+
+	data := testData()
+
+	for _, tc := range tests {
+		q, err := ParseAt(tc.expr, now)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+
+		if got := matching(data, q); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: matched %q, want %q", tc.expr, got, tc.want)
+		}
+
+		if q.String() != tc.expr {
+			t.Errorf("%s: String() = %q", tc.expr, q.String())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		`title:"linear`:     "unterminated quote",
+		"grade:A":           `grade:A: unknown field "grade"`,
+		"score:high":        `score:high: expected a number, got "high"`,
+		"title<abc":         "title<abc: text cannot be compared with <",
+		"due<someday":       `due<someday: unrecognized date "someday"`,
+		"due>+3m":           `due>+3m: unrecognized date "+3m"`,
+		"type<test":         "type<test: only :, = and != apply to this field, not <",
+		"is:late":           `is:late: unknown flag "late"`,
+		"score~5":           "score~5: numbers and dates cannot be compared with ~",
+		"student:anna -a:b": `-a:b: unknown field "a"`,
+	}
+
+	for expr, want := range tests {
+		_, err := Parse(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", expr, err, want)
+		}
+	}
+}
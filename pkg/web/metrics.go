@@ -0,0 +1,147 @@
+package web
+
+import (
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+var (
+	snapshotTimestampDesc = prometheus.NewDesc(
+		"ignitia_snapshot_timestamp_seconds",
+		"When the last successful snapshot being served was taken, in seconds since the epoch.",
+		nil, nil)
+
+	snapshotAgeDesc = prometheus.NewDesc(
+		"ignitia_snapshot_age_seconds",
+		"How long ago the last successful snapshot being served was taken.",
+		nil, nil)
+
+	studentLabels = []string{"student_id", "student"}
+	courseLabels  = []string{"student_id", "student", "course_id", "course"}
+
+	studentIncompleteDesc = prometheus.NewDesc(
+		"ignitia_student_incomplete_courses",
+		"Courses with incomplete assignments, by student.",
+		studentLabels, nil)
+
+	studentDueDesc = prometheus.NewDesc(
+		"ignitia_student_due_assignments",
+		"Assignments due and not yet finished, by student.",
+		studentLabels, nil)
+
+	studentOverdueDesc = prometheus.NewDesc(
+		"ignitia_student_overdue_assignments",
+		"Assignments overdue past the grace period, by student.",
+		studentLabels, nil)
+
+	studentCompletedDesc = prometheus.NewDesc(
+		"ignitia_student_completed_this_week_assignments",
+		"Assignments finished during the current week, by student.",
+		studentLabels, nil)
+
+	courseIncompleteDesc = prometheus.NewDesc(
+		"ignitia_course_incomplete_assignments",
+		"Incomplete assignments, by course.",
+		courseLabels, nil)
+
+	courseDueDesc = prometheus.NewDesc(
+		"ignitia_course_due_assignments",
+		"Assignments due and not yet finished, by course.",
+		courseLabels, nil)
+
+	courseOverdueDesc = prometheus.NewDesc(
+		"ignitia_course_overdue_assignments",
+		"Assignments overdue past the grace period, by course.",
+		courseLabels, nil)
+
+	courseCompletedDesc = prometheus.NewDesc(
+		"ignitia_course_completed_this_week_assignments",
+		"Assignments finished during the current week, by course.",
+		courseLabels, nil)
+
+	courseStatusDesc = prometheus.NewDesc(
+		"ignitia_course_assignments",
+		`Assignments by course and status; statuses the portal was not known to use are "unknown".`,
+		[]string{"student_id", "student", "course_id", "course", "status"}, nil)
+
+	courseScoreDesc = prometheus.NewDesc(
+		"ignitia_course_score",
+		"Current score in the course, as reported by the portal.",
+		courseLabels, nil)
+
+	courseProgressDesc = prometheus.NewDesc(
+		"ignitia_course_progress",
+		"Current progress through the course, as reported by the portal.",
+		courseLabels, nil)
+)
+
+// metrics exports gauges computed from the session's cached data whenever
+// they are scraped.
+type metrics struct{ session *Session }
+
+func (m metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		snapshotTimestampDesc, snapshotAgeDesc,
+		studentIncompleteDesc, studentDueDesc, studentOverdueDesc, studentCompletedDesc,
+		courseIncompleteDesc, courseDueDesc, courseOverdueDesc, courseCompletedDesc, courseStatusDesc,
+		courseScoreDesc, courseProgressDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (m metrics) Collect(ch chan<- prometheus.Metric) {
+	m.session.mu.RLock()
+	data, asOf := m.session.data, m.session.asOf
+	m.session.mu.RUnlock()
+
+	if asOf.IsZero() {
+		return
+	}
+
+	ch <- gauge(snapshotTimestampDesc, float64(asOf.UnixNano())/float64(time.Second))
+	ch <- gauge(snapshotAgeDesc, time.Since(asOf).Seconds())
+
+	for _, student := range data.Students {
+		collectStudent(ch, student)
+	}
+}
+
+func collectStudent(ch chan<- prometheus.Metric, student *model.Student) {
+	labels := []string{strconv.Itoa(student.ID), html.UnescapeString(student.DisplayName)}
+
+	ch <- gauge(studentIncompleteDesc, float64(student.IncompleteCourses()), labels...)
+	ch <- gauge(studentDueDesc, float64(student.DueAssignments()), labels...)
+	ch <- gauge(studentOverdueDesc, float64(student.OverdueAssignments()), labels...)
+	ch <- gauge(studentCompletedDesc, float64(student.CompletedThisWeek()), labels...)
+
+	for _, course := range student.Courses {
+		labels := append(labels[:2:2], strconv.Itoa(course.ID), html.UnescapeString(course.Title))
+
+		ch <- gauge(courseIncompleteDesc, float64(course.IncompleteAssignments()), labels...)
+		ch <- gauge(courseDueDesc, float64(course.DueAssignments()), labels...)
+		ch <- gauge(courseOverdueDesc, float64(course.OverdueAssignments()), labels...)
+		ch <- gauge(courseCompletedDesc, float64(course.CompletedThisWeek()), labels...)
+
+		statuses := map[string]int{}
+		for _, a := range course.Assignments {
+			statuses[a.Status.Slug()]++
+		}
+
+		for status, n := range statuses {
+			ch <- gauge(courseStatusDesc, float64(n), append(labels[:4:4], status)...)
+		}
+
+		ch <- gauge(courseScoreDesc, course.Score, labels...)
+		ch <- gauge(courseProgressDesc, course.Progress, labels...)
+	}
+}
+
+func gauge(desc *prometheus.Desc, value float64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
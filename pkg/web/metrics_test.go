@@ -0,0 +1,115 @@
+package web
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+func TestMetrics(t *testing.T) {
+	date := func(days int) string { return time.Now().AddDate(0, 0, days).Format("2006-01-02") }
+
+	data := model.Data{
+		AsOf: time.Now(),
+		Students: map[int]*model.Student{1: {ID: 1, DisplayName: "Anna &amp; Ben", Courses: map[int]*model.Course{
+			7: {ID: 7, StudentID: 1, Title: "Math", Score: 88.5, Progress: 40, Assignments: map[int]*model.Assignment{
+				1: {ID: 1, Due: date(-60), Status: model.StatusNotStarted},
+				2: {ID: 2, Due: date(-1), Status: model.StatusInProgress},
+				3: {ID: 3, Due: date(0), Completed: date(0), Status: model.StatusCompleted},
+			}},
+			// A second course with the same title is told apart by its id.
+			8: {ID: 8, StudentID: 1, Title: "Math", Assignments: map[int]*model.Assignment{
+				4: {ID: 4, Due: date(30), Status: model.StatusNotStarted},
+			}},
+			9: {ID: 9, StudentID: 1, Title: "Art", Score: 97, Progress: 100, Assignments: map[int]*model.Assignment{
+				5: {ID: 5, Due: date(-40), Completed: date(-40), Status: model.StatusGraded},
+				6: {ID: 6, Due: date(-40), Status: "Excused"},
+			}},
+		}}},
+	}
+
+	s := NewSession(&collector{data: data})
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `
+# HELP ignitia_student_incomplete_courses Courses with incomplete assignments, by student.
+# TYPE ignitia_student_incomplete_courses gauge
+ignitia_student_incomplete_courses{student="Anna & Ben",student_id="1"} 2
+# HELP ignitia_student_due_assignments Assignments due and not yet finished, by student.
+# TYPE ignitia_student_due_assignments gauge
+ignitia_student_due_assignments{student="Anna & Ben",student_id="1"} 2
+# HELP ignitia_student_overdue_assignments Assignments overdue past the grace period, by student.
+# TYPE ignitia_student_overdue_assignments gauge
+ignitia_student_overdue_assignments{student="Anna & Ben",student_id="1"} 1
+# HELP ignitia_student_completed_this_week_assignments Assignments finished during the current week, by student.
+# TYPE ignitia_student_completed_this_week_assignments gauge
+ignitia_student_completed_this_week_assignments{student="Anna & Ben",student_id="1"} 1
+# HELP ignitia_course_incomplete_assignments Incomplete assignments, by course.
+# TYPE ignitia_course_incomplete_assignments gauge
+ignitia_course_incomplete_assignments{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 0
+ignitia_course_incomplete_assignments{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 2
+ignitia_course_incomplete_assignments{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 1
+# HELP ignitia_course_due_assignments Assignments due and not yet finished, by course.
+# TYPE ignitia_course_due_assignments gauge
+ignitia_course_due_assignments{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 0
+ignitia_course_due_assignments{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 2
+ignitia_course_due_assignments{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 0
+# HELP ignitia_course_overdue_assignments Assignments overdue past the grace period, by course.
+# TYPE ignitia_course_overdue_assignments gauge
+ignitia_course_overdue_assignments{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 0
+ignitia_course_overdue_assignments{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 1
+ignitia_course_overdue_assignments{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 0
+# HELP ignitia_course_completed_this_week_assignments Assignments finished during the current week, by course.
+# TYPE ignitia_course_completed_this_week_assignments gauge
+ignitia_course_completed_this_week_assignments{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 0
+ignitia_course_completed_this_week_assignments{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 1
+ignitia_course_completed_this_week_assignments{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 0
+# HELP ignitia_course_assignments Assignments by course and status; statuses the portal was not known to use are "unknown".
+# TYPE ignitia_course_assignments gauge
+ignitia_course_assignments{course="Art",course_id="9",status="graded",student="Anna & Ben",student_id="1"} 1
+ignitia_course_assignments{course="Art",course_id="9",status="unknown",student="Anna & Ben",student_id="1"} 1
+ignitia_course_assignments{course="Math",course_id="7",status="completed",student="Anna & Ben",student_id="1"} 1
+ignitia_course_assignments{course="Math",course_id="7",status="in-progress",student="Anna & Ben",student_id="1"} 1
+ignitia_course_assignments{course="Math",course_id="7",status="not-started",student="Anna & Ben",student_id="1"} 1
+ignitia_course_assignments{course="Math",course_id="8",status="not-started",student="Anna & Ben",student_id="1"} 1
+# HELP ignitia_course_score Current score in the course, as reported by the portal.
+# TYPE ignitia_course_score gauge
+ignitia_course_score{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 97
+ignitia_course_score{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 88.5
+ignitia_course_score{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 0
+# HELP ignitia_course_progress Current progress through the course, as reported by the portal.
+# TYPE ignitia_course_progress gauge
+ignitia_course_progress{course="Art",course_id="9",student="Anna & Ben",student_id="1"} 100
+ignitia_course_progress{course="Math",course_id="7",student="Anna & Ben",student_id="1"} 40
+ignitia_course_progress{course="Math",course_id="8",student="Anna & Ben",student_id="1"} 0
+`
+
+	// The snapshot's timestamp and age change with the clock, so only their
+	// presence is checked.
+	if err := testutil.CollectAndCompare(metrics{session: s}, strings.NewReader(want),
+		"ignitia_student_incomplete_courses", "ignitia_student_due_assignments",
+		"ignitia_student_overdue_assignments", "ignitia_student_completed_this_week_assignments",
+		"ignitia_course_incomplete_assignments", "ignitia_course_due_assignments",
+		"ignitia_course_overdue_assignments", "ignitia_course_completed_this_week_assignments",
+		"ignitia_course_assignments", "ignitia_course_score", "ignitia_course_progress",
+	); err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(metrics{session: s},
+		"ignitia_snapshot_timestamp_seconds", "ignitia_snapshot_age_seconds"); n != 2 {
+		t.Errorf("collected %d snapshot gauges, want 2", n)
+	}
+}
+
+func TestMetricsBeforeFirstSnapshot(t *testing.T) {
+	if n := testutil.CollectAndCount(metrics{session: NewSession(&collector{})}); n != 0 {
+		t.Errorf("collected %d metrics before any snapshot, want none", n)
+	}
+}
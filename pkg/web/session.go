@@ -1,19 +1,26 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/safehtml"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/jw4/ignitia.go/pkg/model"
+	"github.com/jw4/ignitia.go/pkg/query"
 )
 
 // Option represents a function that can modify a session.
@@ -25,13 +32,28 @@ func Assets(path string) Option { return func(s *Session) { s.assets = path } }
 // Templates configures the template files root folder.
 func Templates(path string) Option { return func(s *Session) { s.templates = path } }
 
+// PollInterval configures how often Watch refreshes from backends that cannot
+// push updates; zero or less keeps the default of a minute.
+func PollInterval(d time.Duration) Option {
+	return func(s *Session) {
+		if d > 0 {
+			s.pollInterval = d
+		}
+	}
+}
+
+// RequestTimeout limits how long a request may wait on the backend; zero
+// leaves it to the client.
+func RequestTimeout(d time.Duration) Option { return func(s *Session) { s.requestTimeout = d } }
+
 // NewSession returns a Session.
 func NewSession(collector Collector, opts ...Option) *Session {
 	ses := &Session{
-		DebugWriter: os.Stdout,
-		assets:      "public",
-		templates:   "templates",
-		coll:        collector,
+		DebugWriter:  os.Stdout,
+		assets:       "public",
+		templates:    "templates",
+		pollInterval: time.Minute,
+		coll:         collector,
 	}
 
 	for _, opt := range opts {
@@ -43,11 +65,15 @@ func NewSession(collector Collector, opts ...Option) *Session {
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("/index", ses.renderIndex)
 	mux.HandleFunc("/report", ses.renderReport)
+	mux.HandleFunc("/snapshots", ses.renderSnapshots)
+	mux.HandleFunc("/target", ses.renderTarget)
 	ses.mux = mux
 
 	return ses
 }
 
+var errNoHistory = errors.New("snapshot history is not supported by this backend")
+
 type Collector interface {
 	Reset() error
 	Data() (model.Data, error)
@@ -59,10 +85,16 @@ type Session struct {
 
 	coll Collector
 
+	mu   sync.RWMutex
 	data model.Data
+	live bool
+	// asOf is when the last snapshot read without errors was taken.
+	asOf time.Time
 
-	assets    string
-	templates string
+	assets         string
+	templates      string
+	pollInterval   time.Duration
+	requestTimeout time.Duration
 
 	mux http.Handler
 }
@@ -77,39 +109,162 @@ func (s *Session) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 }
 
 // Refresh updates the cached data.
-func (s *Session) Refresh() error {
+func (s *Session) Refresh() error { return s.RefreshContext(context.Background()) }
+
+// RefreshContext updates the cached data, abandoning the update once ctx is
+// done.
+func (s *Session) RefreshContext(ctx context.Context) error {
 	var err error
+	if r, ok := s.coll.(model.ContextResetter); ok {
+		err = r.ResetContext(ctx)
+	} else {
+		err = s.coll.Reset()
+	}
 
-	if err = s.coll.Reset(); err != nil {
+	if err != nil {
 		return err
 	}
 
-	s.data, err = s.coll.Data()
+	// An abandoned read leaves the cache as it was, and a failed one keeps the
+	// last good data, marked with the error.
+	data, err := model.DataContext(ctx, s.coll)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return err
+	case err != nil:
+		s.fail(err)
+	default:
+		s.update(data)
+	}
 
 	return err
 }
 
+// Watch keeps the cached data current until stop is called, following the
+// backend when it implements model.Watcher and polling it otherwise. While
+// watching, reports are served from the cache.
+func (s *Session) Watch() (func(), error) {
+	if err := s.Refresh(); err != nil {
+		fmt.Fprintf(s.DebugWriter, "Error refreshing: %v\n", err)
+	}
+
+	var stop func()
+
+	if watcher, ok := s.coll.(model.Watcher); ok {
+		var err error
+		if stop, err = watcher.Watch(s.update); err != nil {
+			return nil, err
+		}
+	} else {
+		ticker := time.NewTicker(s.pollInterval)
+		done := make(chan struct{})
+
+		go func() {
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := s.Refresh(); err != nil {
+						fmt.Fprintf(s.DebugWriter, "Error refreshing: %v\n", err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		stop = func() { close(done) }
+	}
+
+	// The gauges are only meaningful while the cache is kept current.
+	collector := metrics{session: s}
+	if err := prometheus.Register(collector); err != nil {
+		stop()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.live = true
+	s.mu.Unlock()
+
+	return func() {
+		prometheus.Unregister(collector)
+
+		s.mu.Lock()
+		s.live = false
+		s.mu.Unlock()
+
+		stop()
+	}, nil
+}
+
+func (s *Session) update(data model.Data) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = data
+
+	if len(data.Errors) == 0 && data.AsOf.After(s.asOf) {
+		s.asOf = data.AsOf
+	}
+}
+
+// fail records err against the cached data, keeping it in place.
+func (s *Session) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.data
+	data.Errors = []error{err}
+	s.data = data
+}
+
+func (s *Session) cached() (model.Data, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data, s.live
+}
+
 // RenderHTML writes the report page out.
 func (s *Session) RenderHTML(out io.Writer) error {
+	data, _ := s.cached()
+	return s.renderHTML(out, &report{Data: data})
+}
+
+// RenderJSON writes the report out in JSON.
+func (s *Session) RenderJSON(out io.Writer) error {
+	data, _ := s.cached()
+	return renderJSON(out, data)
+}
+
+// report is the data shown on the report page, and the query that filtered
+// it, if any.
+type report struct {
+	model.Data
+	Query string
+}
+
+func (s *Session) renderHTML(out io.Writer, data *report) error {
 	pat := filepath.Join(s.templates, "*.gohtml")
 	tmpl, err := template.New("report").Funcs(htmlHelpers).ParseGlob(pat)
 	if err != nil {
 		return fmt.Errorf("parsing %q: %v", pat, err)
 	}
 
-	if err := tmpl.Execute(out, &s.data); err != nil {
+	if err := tmpl.Execute(out, data); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// RenderJSON writes the report out in JSON.
-func (s *Session) RenderJSON(out io.Writer) error {
+func renderJSON(out io.Writer, v interface{}) error {
 	enc := json.NewEncoder(out)
 	enc.SetIndent("", "  ")
 	enc.SetEscapeHTML(false)
-	return enc.Encode(s.data)
+	return enc.Encode(v)
 }
 
 func (s *Session) renderIndex(writer http.ResponseWriter, _ *http.Request) {
@@ -120,36 +275,166 @@ func (s *Session) renderIndex(writer http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
-	if err := tmpl.Execute(writer, &s.data); err != nil {
+	data, _ := s.cached()
+	if err := tmpl.Execute(writer, &data); err != nil {
 		s.renderError(writer, fmt.Errorf("executing template: %v", err))
 		return
 	}
 }
 
 func (s *Session) renderReport(writer http.ResponseWriter, req *http.Request) {
-	if err := s.Refresh(); err != nil {
+	data, err := s.reportData(req)
+	if err != nil {
 		s.renderError(writer, err)
 		return
 	}
 
+	expr := req.FormValue("q")
+	if expr != "" {
+		q, err := query.Parse(expr)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data = data.Filter(q.Match)
+	}
+
 	if should(req.FormValue("json")) {
 		writer.Header().Set("Content-Type", "application/json")
-		if err := s.RenderJSON(writer); err != nil {
+		if err := renderJSON(writer, data); err != nil {
 			s.renderError(writer, err)
 		}
 
 		return
 	}
 
-	if err := s.RenderHTML(writer); err != nil {
+	if err := s.renderHTML(writer, &report{Data: data, Query: expr}); err != nil {
 		s.renderError(writer, err)
 		return
 	}
 }
 
+// reportData returns the latest data, or the data as it was at the time given
+// in the as_of query parameter.
+func (s *Session) reportData(req *http.Request) (model.Data, error) {
+	ctx := req.Context()
+	if s.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+		defer cancel()
+	}
+
+	asOf := req.FormValue("as_of")
+	if asOf == "" {
+		if data, live := s.cached(); live {
+			return data, nil
+		}
+
+		err := s.RefreshContext(ctx)
+		data, _ := s.cached()
+
+		return data, err
+	}
+
+	history, ok := s.coll.(model.History)
+	if !ok {
+		return model.Data{}, errNoHistory
+	}
+
+	when, err := model.ParseAsOf(asOf)
+	if err != nil {
+		return model.Data{}, err
+	}
+
+	return model.DataAsOfContext(ctx, history, when)
+}
+
+func (s *Session) renderSnapshots(writer http.ResponseWriter, _ *http.Request) {
+	history, ok := s.coll.(model.History)
+	if !ok {
+		s.renderError(writer, errNoHistory)
+		return
+	}
+
+	snapshots, err := history.Snapshots()
+	if err != nil {
+		s.renderError(writer, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if err := renderJSON(writer, snapshots); err != nil {
+		s.renderError(writer, err)
+	}
+}
+
+// renderTarget writes out in JSON what it takes to reach the grade given in
+// the grade query parameter, a letter or a score, in the course given by the
+// student and course parameters.
+func (s *Session) renderTarget(writer http.ResponseWriter, req *http.Request) {
+	data, err := s.reportData(req)
+	if err != nil {
+		s.renderError(writer, err)
+		return
+	}
+
+	course, ok := findCourse(data, req.FormValue("student"), req.FormValue("course"))
+	if !ok {
+		http.NotFound(writer, req)
+		return
+	}
+
+	grade, err := model.CurrentPolicy().Grading.ParseTarget(req.FormValue("grade"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if err := renderJSON(writer, course.Target(grade)); err != nil {
+		s.renderError(writer, err)
+	}
+}
+
+func findCourse(data model.Data, studentID, courseID string) (*model.Course, bool) {
+	sid, err := strconv.Atoi(studentID)
+	if err != nil {
+		return nil, false
+	}
+
+	cid, err := strconv.Atoi(courseID)
+	if err != nil {
+		return nil, false
+	}
+
+	student, ok := data.Students[sid]
+	if !ok {
+		return nil, false
+	}
+
+	course, ok := student.Courses[cid]
+
+	return course, ok
+}
+
+// renderError writes out err with the status it calls for, hiding the
+// details of unexpected errors.
 func (s *Session) renderError(writer http.ResponseWriter, err error) {
-	writer.WriteHeader(http.StatusInternalServerError)
-	fmt.Fprintf(s.DebugWriter, "Error serving page: %v\n", err)
+	status, msg := http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+
+	switch {
+	case errors.Is(err, model.ErrBadTime):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, model.ErrNoSnapshot):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, errNoHistory):
+		status, msg = http.StatusNotImplemented, err.Error()
+	default:
+		fmt.Fprintf(s.DebugWriter, "Error serving page: %v\n", err)
+	}
+
+	http.Error(writer, msg, status)
 }
 
 func should(s string) bool {
@@ -182,6 +467,7 @@ func tolower(s string) string {
 
 var htmlHelpers = template.FuncMap{
 	"htmlsafe": func(s string) template.HTML { return template.HTML(safehtml.HTMLEscaped(s).String()) },
+	"percent":  func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
 	"rawhtml":  func(s string) template.HTML { return template.HTML(s) },
 	"tolower":  tolower,
 }
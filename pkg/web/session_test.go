@@ -0,0 +1,157 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jw4/ignitia.go/pkg/model"
+)
+
+// collector serves data, or fails with err.
+type collector struct {
+	data model.Data
+	err  error
+}
+
+func (c *collector) Reset() error { return nil }
+
+func (c *collector) Data() (model.Data, error) {
+	if c.err != nil {
+		return model.Data{Errors: []error{c.err}, AsOf: time.Now()}, c.err
+	}
+
+	return c.data, nil
+}
+
+func testData() model.Data {
+	return model.Data{
+		AsOf: time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC),
+		Students: map[int]*model.Student{1: {ID: 1, DisplayName: "Anna", Courses: map[int]*model.Course{
+			7: {ID: 7, StudentID: 1, Title: "Math", Assignments: map[int]*model.Assignment{
+				3: {ID: 3, CourseID: 7, StudentID: 1, Title: "Fractions", Type: model.TypeLesson, Status: model.StatusGraded, Score: 90},
+			}},
+		}}},
+	}
+}
+
+// history serves its snapshot to requests as of first or later.
+type history struct {
+	collector
+	first time.Time
+}
+
+func (h *history) Snapshots() ([]time.Time, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+
+	return []time.Time{h.first}, nil
+}
+
+func (h *history) DataAsOf(when time.Time) (model.Data, error) {
+	if when.Before(h.first) {
+		return model.Data{}, model.ErrNoSnapshot
+	}
+
+	return h.data, nil
+}
+
+func TestRefreshKeepsLastGoodData(t *testing.T) {
+	coll := &collector{data: testData()}
+	s := NewSession(coll)
+
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	errPortal := errors.New("portal down")
+	coll.err = errPortal
+
+	if err := s.Refresh(); !errors.Is(err, errPortal) {
+		t.Fatalf("Refresh err = %v, want %v", err, errPortal)
+	}
+
+	data, _ := s.cached()
+	if len(data.Students) != 1 || !data.AsOf.Equal(testData().AsOf) {
+		t.Errorf("cached data replaced: %+v", data)
+	}
+
+	if len(data.Errors) != 1 || !errors.Is(data.Errors[0], errPortal) {
+		t.Errorf("cached errors = %v, want [%v]", data.Errors, errPortal)
+	}
+
+	if !s.asOf.Equal(testData().AsOf) {
+		t.Errorf("asOf = %s, want %s", s.asOf, testData().AsOf)
+	}
+
+	coll.err = nil
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := s.cached(); len(data.Errors) != 0 {
+		t.Errorf("errors kept after a good refresh: %v", data.Errors)
+	}
+}
+
+func TestWatchWithoutPollInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		s := NewSession(&collector{data: testData()}, PollInterval(interval))
+
+		// Polling at the default interval rather than panicking on NewTicker.
+		stop, err := s.Watch()
+		if err != nil {
+			t.Fatalf("interval %s: %v", interval, err)
+		}
+
+		stop()
+
+		if s.pollInterval != time.Minute {
+			t.Errorf("interval %s: polling every %s, want %s", interval, s.pollInterval, time.Minute)
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	current := &collector{data: testData()}
+	past := &history{collector: collector{data: testData()}, first: testData().AsOf}
+	broken := &history{collector: collector{err: errors.New("disk on fire")}}
+
+	tests := []struct {
+		coll   Collector
+		target string
+		status int
+		body   string
+	}{
+		{past, "/report?as_of=yesterday", http.StatusBadRequest, `unrecognized time "yesterday"`},
+		{past, "/target?as_of=soon&student=1&course=7&grade=A", http.StatusBadRequest, `unrecognized time "soon"`},
+		{past, "/report?as_of=2022-08-01", http.StatusNotFound, model.ErrNoSnapshot.Error()},
+		{past, "/target?as_of=2022-08-01&student=1&course=7&grade=A", http.StatusNotFound, model.ErrNoSnapshot.Error()},
+		{current, "/report?as_of=2022-09-02", http.StatusNotImplemented, errNoHistory.Error()},
+		{current, "/snapshots", http.StatusNotImplemented, errNoHistory.Error()},
+		{broken, "/snapshots", http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)},
+		{past, "/report?q=score<", http.StatusBadRequest, ""},
+		{past, "/snapshots", http.StatusOK, "2022-09-01T08:00:00Z"},
+	}
+
+	for _, tc := range tests {
+		s := NewSession(tc.coll)
+		s.DebugWriter = io.Discard
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.target, rec.Code, tc.status)
+		}
+
+		if body := rec.Body.String(); body == "" || !strings.Contains(body, tc.body) {
+			t.Errorf("%s: body = %q, want %q", tc.target, body, tc.body)
+		}
+	}
+}